@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// account は --token で指定した Todoist のアカウント
+// label は複数のアカウントをまとめてレポートするときに、どのアカウントのイベントかを区別するために使う
+type account struct {
+	label string
+	token string
+}
+
+// accountsFlag は --token を複数回指定できるようにする flag.Value
+// "work=TOKEN" のように指定するとラベルを付けられる。ラベルを省略した場合は account1, account2, ... にする
+type accountsFlag []account
+
+func (f *accountsFlag) String() string {
+	labels := make([]string, 0, len(*f))
+	for _, a := range *f {
+		labels = append(labels, a.label)
+	}
+	return strings.Join(labels, ",")
+}
+
+func (f *accountsFlag) Set(value string) error {
+	label, token, ok := strings.Cut(value, "=")
+	if !ok {
+		label, token = fmt.Sprintf("account%d", len(*f)+1), value
+	}
+	if token == "" {
+		return fmt.Errorf("empty token for account %q", label)
+	}
+	for _, a := range *f {
+		if a.label == label {
+			return fmt.Errorf("duplicate account label %q", label)
+		}
+	}
+	*f = append(*f, account{label: label, token: token})
+	return nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"todoistreport/todoist"
+)
+
+const defaultProjectCacheTTL = 1 * time.Hour
+
+// $XDG_CACHE_HOME/todoistreport（未設定なら ~/.cache/todoistreport）
+func cacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("user cache dir error: %w", err)
+	}
+	return filepath.Join(dir, "todoistreport"), nil
+}
+
+// キャッシュのキーにトークンそのものを保存しないようにハッシュ化する
+func tokenHash(apiToken string) string {
+	sum := sha256.Sum256([]byte(apiToken))
+	return hex.EncodeToString(sum[:])
+}
+
+type projectCacheEntry struct {
+	FetchedAt time.Time                   `json:"fetched_at"`
+	Response  todoist.GetProjectsResponse `json:"response"`
+}
+
+// projectCache はトークンごとのプロジェクト一覧をファイルにキャッシュする
+// SyncToken も保存しているので、差分同期に使える
+type projectCache struct {
+	path string
+	ttl  time.Duration
+}
+
+func newProjectCache(ttl time.Duration) (*projectCache, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return &projectCache{path: filepath.Join(dir, "projects.json"), ttl: ttl}, nil
+}
+
+func (c *projectCache) load() (map[string]projectCacheEntry, error) {
+	entries := make(map[string]projectCacheEntry)
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("project cache read error: %w", err)
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("project cache json unmarshall error: %w", err)
+	}
+	return entries, nil
+}
+
+func (c *projectCache) get(apiToken string, now time.Time) (todoist.GetProjectsResponse, bool, error) {
+	entries, err := c.load()
+	if err != nil {
+		return todoist.GetProjectsResponse{}, false, err
+	}
+	entry, ok := entries[tokenHash(apiToken)]
+	if !ok || now.Sub(entry.FetchedAt) > c.ttl {
+		return todoist.GetProjectsResponse{}, false, nil
+	}
+	return entry.Response, true, nil
+}
+
+func (c *projectCache) put(apiToken string, response todoist.GetProjectsResponse, now time.Time) error {
+	entries, err := c.load()
+	if err != nil {
+		// 壊れたキャッシュは作り直す
+		entries = make(map[string]projectCacheEntry)
+	}
+	entries[tokenHash(apiToken)] = projectCacheEntry{FetchedAt: now, Response: response}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("project cache json marshal error: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return fmt.Errorf("project cache dir create error: %w", err)
+	}
+	if err := os.WriteFile(c.path, data, 0o600); err != nil {
+		return fmt.Errorf("project cache write error: %w", err)
+	}
+	return nil
+}
+
+// キャッシュがあればそれを使い、なければプロジェクト一覧を取得してキャッシュする
+// cached はキャッシュから読み込んだかどうか
+// キャッシュの読み書きに失敗してもレポートは出力できるので、警告だけ出して続行する
+func loadProjects(ctx context.Context, client *todoist.Client, cache *projectCache, apiToken string) (response todoist.GetProjectsResponse, cached bool, err error) {
+	if cache != nil {
+		response, ok, err := cache.get(apiToken, time.Now())
+		if err != nil {
+			log.Println(err)
+		}
+		if ok {
+			return response, true, nil
+		}
+	}
+
+	response, err = client.Projects(ctx)
+	if err != nil {
+		return todoist.GetProjectsResponse{}, false, fmt.Errorf("get project error: %w", err)
+	}
+
+	cache.save(apiToken, response)
+	return response, false, nil
+}
+
+// キャッシュしたプロジェクト一覧を SyncToken から差分同期して更新する
+// キャッシュした後に作成されたプロジェクトを、キャッシュの期限切れを待たずに見つけられるようにする
+func refreshProjects(ctx context.Context, client *todoist.Client, cache *projectCache, apiToken string, cached todoist.GetProjectsResponse) (todoist.GetProjectsResponse, error) {
+	response, err := client.UpdateProjects(ctx, cached)
+	if err != nil {
+		return todoist.GetProjectsResponse{}, fmt.Errorf("get project error: %w", err)
+	}
+
+	cache.save(apiToken, response)
+	return response, nil
+}
+
+// キャッシュに書き込む。cache が nil（--no-cache）の場合は何もしない
+func (c *projectCache) save(apiToken string, response todoist.GetProjectsResponse) {
+	if c == nil {
+		return
+	}
+	if err := c.put(apiToken, response, time.Now()); err != nil {
+		log.Println(err)
+	}
+}
+
+// activityCache は過去の週のアクティビティログのページをファイルにキャッシュする
+// 過去の週の完了履歴は変わらないので期限は設けない。今週のページは変わるのでキャッシュしない
+type activityCache struct {
+	dir string
+	now time.Time
+	loc *time.Location
+}
+
+func newActivityCache(now time.Time, loc *time.Location) (*activityCache, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return &activityCache{dir: filepath.Join(dir, "activity"), now: now, loc: loc}, nil
+}
+
+// ページのキャッシュファイルのパスを返す。ページ番号は今週からの相対値なので、キーにはそのページの週の開始日を使う
+// 今週のページと、週の境目の前後1日（Todoist 側のタイムゾーンによってページの週がずれる可能性がある）はキャッシュしない
+func (c *activityCache) path(apiToken string, request pageRequest, eventTypes []string) (string, bool) {
+	if c == nil || request.Page == 0 {
+		return "", false
+	}
+	thisWeek := startOfWeek(c.now, c.loc)
+	if c.now.Sub(thisWeek) < 24*time.Hour || thisWeek.AddDate(0, 0, 7).Sub(c.now) < 24*time.Hour {
+		return "", false
+	}
+
+	weekStart := thisWeek.AddDate(0, 0, -7*request.Page)
+	projectID := request.Project.ID
+	if projectID == "" {
+		projectID = "all"
+	}
+	types := append([]string(nil), eventTypes...)
+	sort.Strings(types)
+	name := fmt.Sprintf("%s_%s_%s_%s.json", projectID, weekStart.Format("20060102"), strings.ReplaceAll(c.loc.String(), "/", "-"), strings.Join(types, "+"))
+	return filepath.Join(c.dir, tokenHash(apiToken), name), true
+}
+
+func (c *activityCache) get(path string) (todoist.GetActivityLogResponse, bool, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return todoist.GetActivityLogResponse{}, false, nil
+	}
+	if err != nil {
+		return todoist.GetActivityLogResponse{}, false, fmt.Errorf("activity cache read error: %w", err)
+	}
+	var response todoist.GetActivityLogResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return todoist.GetActivityLogResponse{}, false, fmt.Errorf("activity cache json unmarshall error: %w", err)
+	}
+	return response, true, nil
+}
+
+func (c *activityCache) put(path string, response todoist.GetActivityLogResponse) error {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("activity cache json marshal error: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("activity cache dir create error: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("activity cache write error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// GitHub の Issue などに貼り付けられるように、日ごとの見出しの下に完了済みのチェックリストとして出力する
+func renderChecklist(w io.Writer, events []Event, opts renderOptions) error {
+	date := withRelativeDates([]column{dateColumn}, opts)[0]
+	var buf strings.Builder
+	for i, group := range groupEvents(events, groupByDay, time.Monday) {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(&buf, "## %s\n\n", group.title)
+		for _, event := range group.events {
+			fmt.Fprintf(&buf, "- [x] %s _%s_\n", escapeMarkdown(event.Content), date.value(event))
+		}
+	}
+
+	if _, err := io.WriteString(w, buf.String()); err != nil {
+		return fmt.Errorf("write error: %w", err)
+	}
+	return nil
+}
+
+var markdownEscaper = strings.NewReplacer(
+	"\\", "\\\\",
+	"`", "\\`",
+	"*", "\\*",
+	"_", "\\_",
+	"[", "\\[",
+	"]", "\\]",
+	"<", "\\<",
+	">", "\\>",
+	"#", "\\#",
+	"|", "\\|",
+	"~", "\\~",
+	"\r\n", " ",
+	"\n", " ",
+)
+
+// タスクの内容がそのままの文字で表示されるように Markdown の記号をエスケープする
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
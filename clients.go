@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// クライアントが記録されていないイベントの表示名
+const unknownClient = "unknown"
+
+type clientCount struct {
+	Client string
+	Count  int
+}
+
+func clientName(client string) string {
+	if client == "" {
+		return unknownClient
+	}
+	return client
+}
+
+// クライアント（端末）ごとの完了数を集計する。完了数の多い順に並べる
+func countByClient(events []Event) []clientCount {
+	counts := make(map[string]int)
+	for _, event := range events {
+		counts[clientName(event.Client)]++
+	}
+
+	result := make([]clientCount, 0, len(counts))
+	for client, count := range counts {
+		result = append(result, clientCount{Client: client, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Client < result[j].Client
+	})
+	return result
+}
+
+func renderClientSummary(w io.Writer, counts []clientCount, format string) error {
+	var buf bytes.Buffer
+	switch format {
+	case formatMarkdown:
+		buf.WriteString("\n| Client | Count |\n| --- | --- |\n")
+		for _, c := range counts {
+			fmt.Fprintf(&buf, "| %s | %d |\n", escapeMarkdownCell(c.Client), c.Count)
+		}
+	default:
+		buf.WriteString("\n")
+		for _, c := range counts {
+			fmt.Fprintf(&buf, "%s %d\n", c.Client, c.Count)
+		}
+	}
+
+	if _, err := buf.WriteTo(w); err != nil {
+		return fmt.Errorf("write error: %w", err)
+	}
+	return nil
+}
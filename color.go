@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// --color の値
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+var colorValues = []string{colorAuto, colorAlways, colorNever}
+
+func validateColor(color string) error {
+	for _, c := range colorValues {
+		if color == c {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown color %q (available: %s)", color, strings.Join(colorValues, ", "))
+}
+
+// 色を付けて出力するかを決める
+// auto の場合は、出力先が端末で NO_COLOR 環境変数が設定されていない場合だけ色を付ける
+func useColor(color string, w io.Writer) bool {
+	switch color {
+	case colorAlways:
+		return true
+	case colorNever:
+		return false
+	default:
+		return os.Getenv("NO_COLOR") == "" && isTerminal(w)
+	}
+}
+
+// 出力先が端末かどうか。ファイルやパイプ、サーバーモードのレスポンスは端末ではない
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+)
+
+// periodStats は --compare で比較する期間ごとの完了数
+type periodStats struct {
+	label  string
+	total  int
+	perDay float64
+}
+
+// 1日あたりの完了数は期間の日数で割る。今月のように期間が終わっていない場合は now までの日数で割る
+func newPeriodStats(p period, total int, now time.Time) periodStats {
+	end := p.until
+	if now.Before(end) {
+		end = now
+	}
+	days := end.Sub(p.since).Hours() / 24
+	if days < 1 {
+		days = 1
+	}
+	return periodStats{label: p.label(), total: total, perDay: float64(total) / days}
+}
+
+// 比較対象の完了数が0の場合は増減率を計算できないので n/a にする
+func formatChange(current, previous periodStats) string {
+	diff := current.total - previous.total
+	if previous.total == 0 {
+		return fmt.Sprintf("%+d (n/a)", diff)
+	}
+	return fmt.Sprintf("%+d (%+.1f%%)", diff, float64(diff)*100/float64(previous.total))
+}
+
+func renderComparison(w io.Writer, current, previous periodStats, format string) error {
+	var buf bytes.Buffer
+	switch format {
+	case formatMarkdown:
+		buf.WriteString("\n| Period | Tasks | Per day |\n| --- | --- | --- |\n")
+		for _, s := range []periodStats{current, previous} {
+			fmt.Fprintf(&buf, "| %s | %d | %.2f |\n", s.label, s.total, s.perDay)
+		}
+		fmt.Fprintf(&buf, "\nChange: **%s**\n", formatChange(current, previous))
+	default:
+		buf.WriteString("\n")
+		for _, s := range []periodStats{current, previous} {
+			fmt.Fprintf(&buf, "%s %d tasks (%.2f/day)\n", s.label, s.total, s.perDay)
+		}
+		fmt.Fprintf(&buf, "Change: %s\n", formatChange(current, previous))
+	}
+
+	if _, err := buf.WriteTo(w); err != nil {
+		return fmt.Errorf("write error: %w", err)
+	}
+	return nil
+}
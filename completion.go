@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// completionRate は --completion-rate で出力する、期間中に追加したタスクと完了したタスクの数
+type completionRate struct {
+	added     int
+	completed int
+	// 期間中に追加して、期間中に完了したタスクの数
+	addedAndCompleted int
+	// 期間より前に追加して、期間中に完了したタスクの数
+	completedFromBefore int
+}
+
+// added は期間中の added イベント、completed は期間中のイベント（completed 以外は数えない）
+// 期間より前に追加したタスクの完了は完了率に含めないので、完了率は100%を超えない
+func newCompletionRate(added, completed []Event) completionRate {
+	addedTasks := make(map[string]bool)
+	for _, event := range added {
+		addedTasks[event.TaskID] = true
+	}
+
+	rate := completionRate{added: len(addedTasks)}
+	completedTasks := make(map[string]bool)
+	for _, event := range completed {
+		if event.EventType != "completed" || completedTasks[event.TaskID] {
+			continue
+		}
+		// 完了を取り消してもう一度完了したタスクは1つと数える
+		completedTasks[event.TaskID] = true
+		rate.completed++
+		if addedTasks[event.TaskID] {
+			rate.addedAndCompleted++
+		} else {
+			rate.completedFromBefore++
+		}
+	}
+	return rate
+}
+
+// 期間中に1つもタスクを追加していない場合は完了率を計算できないので n/a にする
+func (r completionRate) ratio() string {
+	if r.added == 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.1f%%", float64(r.addedAndCompleted)*100/float64(r.added))
+}
+
+func renderCompletionRate(w io.Writer, r completionRate, format string) error {
+	var buf bytes.Buffer
+	switch format {
+	case formatMarkdown:
+		buf.WriteString("\n| Added | Completed | Completion rate |\n| --- | --- | --- |\n")
+		fmt.Fprintf(&buf, "| %d | %d | %s |\n", r.added, r.completed, r.ratio())
+		fmt.Fprintf(&buf, "\n%d of %d added tasks were completed.", r.addedAndCompleted, r.added)
+		if r.completedFromBefore > 0 {
+			fmt.Fprintf(&buf, " %d completed tasks were added before this period and are not counted in the rate.", r.completedFromBefore)
+		}
+		buf.WriteString("\n")
+	default:
+		fmt.Fprintf(&buf, "\nAdded %d, completed %d, completion rate %s (%d of %d added tasks completed)\n",
+			r.added, r.completed, r.ratio(), r.addedAndCompleted, r.added)
+		if r.completedFromBefore > 0 {
+			fmt.Fprintf(&buf, "Note: %d completed tasks were added before this period and are not counted in the rate\n", r.completedFromBefore)
+		}
+	}
+
+	if _, err := buf.WriteTo(w); err != nil {
+		return fmt.Errorf("write error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// fileConfig は設定ファイル（~/.config/todoistreport/config.json）の内容
+type fileConfig struct {
+	Token   string `json:"token"`
+	Project string `json:"project"`
+	TZ      string `json:"tz"`
+}
+
+// $XDG_CONFIG_HOME/todoistreport/config.json（未設定なら ~/.config/todoistreport/config.json）
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "todoistreport", "config.json")
+}
+
+// 設定ファイルを読み込む。ファイルが無い場合は空の設定を返す
+// トークンを含むので、所有者以外が読める権限になっている場合は警告する
+func loadFileConfig(path string) (fileConfig, error) {
+	if path == "" {
+		return fileConfig{}, nil
+	}
+
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fileConfig{}, nil
+	}
+	if err != nil {
+		return fileConfig{}, fmt.Errorf("config file stat error: %w", err)
+	}
+	if perm := info.Mode().Perm(); perm&0o077 != 0 {
+		log.Printf("warning: config file %s is accessible by other users (mode %#o). run `chmod 600 %s`\n", path, perm, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fileConfig{}, fmt.Errorf("config file read error: %w", err)
+	}
+
+	var fc fileConfig
+	if err := json.Unmarshal(data, &fc); err != nil {
+		return fileConfig{}, fmt.Errorf("config file json unmarshall error: %w", err)
+	}
+	return fc, nil
+}
+
+// 優先順位は フラグ > 設定ファイル > 環境変数
+// setFlags はコマンドラインで明示的に指定されたフラグ名
+func (cfg *config) applyFileConfig(fc fileConfig, setFlags map[string]bool) {
+	if !setFlags["token"] {
+		token := fc.Token
+		if token == "" {
+			token = os.Getenv("TODOIST_API_TOKEN")
+		}
+		if token != "" {
+			cfg.accounts = []account{{label: "account1", token: token}}
+		}
+	}
+	if !setFlags["project"] && fc.Project != "" {
+		cfg.projectName = fc.Project
+	}
+	if !setFlags["tz"] && fc.TZ != "" {
+		cfg.tz = fc.TZ
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// メールにそのまま貼り付けられるように、挨拶・まとめの文・日ごとの件数・結びの文をプレーンテキストで出力する
+// 日ごとの件数は1件以上完了した日だけ出力する
+func renderDigest(w io.Writer, r report) error {
+	counts := countByDay(r.Events, r.period)
+	var active []dailyCount
+	var best dailyCount
+	for _, c := range counts {
+		if c.Count == 0 {
+			continue
+		}
+		active = append(active, c)
+		// 同じ件数の日が複数ある場合は最初の日にする
+		if c.Count > best.Count {
+			best = c
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("Hi,\n\n")
+	fmt.Fprintf(&buf, "Here is your Todoist digest for %s (%s).\n\n", r.Project, r.Period)
+	if len(active) == 0 {
+		fmt.Fprintf(&buf, "You did not complete any tasks %s.\n", digestPeriodPhrase(r.period))
+	} else {
+		fmt.Fprintf(&buf, "You completed %s %s across %s, most productive on %s with %d.\n",
+			plural(len(r.Events), "task"), digestPeriodPhrase(r.period), plural(len(active), "day"),
+			digestDayPhrase(best.Date, r.period), best.Count)
+		buf.WriteString("\nDaily breakdown:\n")
+		for _, c := range active {
+			fmt.Fprintf(&buf, "  %s %s  %d\n", c.Date.Format(dateLayout), c.Date.Format("Mon"), c.Count)
+		}
+	}
+	buf.WriteString("\nKeep up the good work!\n")
+
+	if _, err := buf.WriteTo(w); err != nil {
+		return fmt.Errorf("write error: %w", err)
+	}
+	return nil
+}
+
+// 年単位の期間は "in 2023"、月単位の期間は "in May 2023"、それ以外は開始日と終了日で表す
+func digestPeriodPhrase(p period) string {
+	if p.isYear() {
+		return "in " + p.since.Format(yearLayout)
+	}
+	if p.isMonth() {
+		return "in " + p.since.Format("January 2006")
+	}
+	return fmt.Sprintf("between %s and %s", p.since.Format(dateLayout), p.until.Add(-1).Format(dateLayout))
+}
+
+// 月単位の期間は "the 14th" のように日だけ、それ以外は日付で表す
+func digestDayPhrase(day time.Time, p period) string {
+	if p.isMonth() {
+		return "the " + ordinal(day.Day())
+	}
+	return day.Format(dateLayout)
+}
+
+func ordinal(n int) string {
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"todoistreport/todoist"
+)
+
+// fetchPlan は --dry-run で表示する、実際に取得する予定の内容
+type fetchPlan struct {
+	account    string
+	api        string
+	projects   []todoist.Project
+	startPage  int
+	endPage    int
+	period     period
+	loc        *time.Location
+	eventTypes []string
+	sharing    sharingFilter
+	format     string
+	// --slack-webhook を指定した場合は、投稿する内容を表示するためにイベントを取得する
+	slack bool
+}
+
+func renderPlan(w io.Writer, plan fetchPlan) error {
+	var buf bytes.Buffer
+	if plan.slack {
+		buf.WriteString("dry run: activity logs are fetched to print the slack payload, which is not posted\n")
+	} else {
+		buf.WriteString("dry run: activity log requests are not sent\n")
+	}
+	if plan.account != "" {
+		fmt.Fprintf(&buf, "account:     %s\n", plan.account)
+	}
+
+	for _, project := range plan.projects {
+		if project.ID == "" {
+			buf.WriteString("project:     (all projects)\n")
+			continue
+		}
+		fmt.Fprintf(&buf, "project:     %s (id=%s)\n", project.Name, project.ID)
+	}
+	fmt.Fprintf(&buf, "period:      %s - %s\n", plan.period.since.Format(dateLayout), plan.period.until.Format(dateLayout))
+	fmt.Fprintf(&buf, "timezone:    %s\n", plan.loc)
+	fmt.Fprintf(&buf, "api:         %s\n", plan.api)
+	if plan.api == apiSync {
+		fmt.Fprintf(&buf, "pages:       %d..%d (%d requests)\n", plan.startPage, plan.endPage, len(plan.projects)*(plan.endPage-plan.startPage+1))
+	} else {
+		fmt.Fprintf(&buf, "chunks:      %d (up to %d months each)\n", len(splitPeriod(plan.period, completedItemsMaxMonths)), completedItemsMaxMonths)
+	}
+
+	eventTypes := plan.eventTypes
+	if len(eventTypes) == 0 {
+		eventTypes = []string{"completed"}
+	}
+	fmt.Fprintf(&buf, "event types: %s\n", strings.Join(eventTypes, ", "))
+	fmt.Fprintf(&buf, "sharing:     %s\n", plan.sharing)
+	fmt.Fprintf(&buf, "format:      %s\n", plan.format)
+
+	if _, err := buf.WriteTo(w); err != nil {
+		return fmt.Errorf("write error: %w", err)
+	}
+	return nil
+}
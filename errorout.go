@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+
+	"todoistreport/todoist"
+)
+
+// --errors の値
+const (
+	errorsText = "text"
+	errorsJSON = "json"
+)
+
+// 終了コードの一覧。--help に出力する
+const exitCodesHelp = "exit codes: 1 error, 2 auth error, 3 project not found, 4 no events (--fail-if-empty), 5 rate limited, 6 network error, 7 --exec command failed, 130 interrupted"
+
+func validateErrorsFormat(format string) error {
+	switch format {
+	case errorsText, errorsJSON:
+		return nil
+	default:
+		return fmt.Errorf("unknown errors format %q (available: %s, %s)", format, errorsText, errorsJSON)
+	}
+}
+
+// errorReporter は終了する原因になったエラーを標準エラー出力に出力して終了する
+// json の場合は {"error": "...", "code": N} の1行にして、スクリプトからエラーの種類を判定できるようにする
+type errorReporter struct {
+	format string
+	logger *log.Logger
+}
+
+func (r errorReporter) exit(err error, code int) {
+	if r.format == errorsJSON {
+		_ = json.NewEncoder(os.Stderr).Encode(struct {
+			Error string `json:"error"`
+			Code  int    `json:"code"`
+		}{Error: err.Error(), Code: code})
+	} else {
+		r.logger.Println(err)
+	}
+	os.Exit(code)
+}
+
+// エラーの種類ごとの終了コード
+func exitCode(err error) int {
+	var rateLimitErr *todoist.RateLimitError
+	var netErr net.Error
+	var execErr *execError
+	switch {
+	case errors.Is(err, todoist.ErrNoAPIToken) || errors.Is(err, todoist.ErrInvalidAPIToken):
+		return exitCodeAuthError
+	case errors.Is(err, todoist.ErrProjectNotFound):
+		return exitCodeProjectNotFound
+	case errors.Is(err, errNoEvents):
+		return exitCodeNoEvents
+	case errors.As(err, &rateLimitErr):
+		return exitCodeRateLimited
+	case errors.As(err, &execErr):
+		return exitCodeExecFailed
+	case errors.As(err, &netErr):
+		return exitCodeNetworkError
+	default:
+		return exitCodeError
+	}
+}
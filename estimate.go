@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// タスクの内容に含まれる (2h), [30m], (1h30m) のような見積もり時間
+const defaultEstimatePattern = `[(\[]((?:\d+(?:\.\d+)?[hm])+)[)\]]`
+
+// --estimate-pattern の正規表現をコンパイルする。1つ目のグループが time.ParseDuration で読める時間になっている必要がある
+func compileEstimatePattern(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("estimate pattern compile error: %w", err)
+	}
+	if re.NumSubexp() < 1 {
+		return nil, fmt.Errorf("estimate pattern %q must have a capture group for the duration", pattern)
+	}
+	return re, nil
+}
+
+// タスクの内容から見積もり時間を取り出して合計する。見積もりが無い、または読めないタスクは0として扱う
+func sumEstimates(events []Event, re *regexp.Regexp) time.Duration {
+	var total time.Duration
+	for _, event := range events {
+		m := re.FindStringSubmatch(event.Content)
+		if m == nil {
+			continue
+		}
+		d, err := time.ParseDuration(m[1])
+		if err != nil {
+			continue
+		}
+		total += d
+	}
+	return total
+}
+
+// 12h30m0s ではなく 12h30m のように表示する
+func formatEstimate(d time.Duration) string {
+	s := d.Round(time.Minute).String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path"
+
+	"todoistreport/todoist"
+)
+
+// projectExcluder は --exclude, --no-inbox で対象から外すプロジェクト
+type projectExcluder struct {
+	// プロジェクト名、または * ? [] を含む path.Match のパターン
+	patterns []string
+	inbox    bool
+}
+
+// パターンの誤りは API にリクエストする前に知らせる
+func newProjectExcluder(patterns []string, inbox bool) (projectExcluder, error) {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return projectExcluder{}, fmt.Errorf("exclude pattern %q error: %w", pattern, err)
+		}
+	}
+	return projectExcluder{patterns: patterns, inbox: inbox}, nil
+}
+
+func (e projectExcluder) match(project todoist.Project) bool {
+	if e.inbox && project.InboxProject {
+		return true
+	}
+	for _, pattern := range e.patterns {
+		if ok, _ := path.Match(pattern, project.Name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// 指定したプロジェクトから除外するプロジェクトを取り除く
+func (e projectExcluder) filter(projects []todoist.Project) []todoist.Project {
+	var result []todoist.Project
+	for _, project := range projects {
+		if project.ID != "" && e.match(project) {
+			log.Printf("project %q is excluded\n", project.Name)
+			continue
+		}
+		result = append(result, project)
+	}
+	return result
+}
+
+// アカウント全体を対象にする場合はプロジェクトで絞り込んで取得できないので、イベントのプロジェクトで取り除くための ID
+func (e projectExcluder) projectIDs(projects []todoist.Project) map[string]bool {
+	ids := make(map[string]bool)
+	for _, project := range projects {
+		if e.match(project) {
+			ids[project.ID] = true
+		}
+	}
+	return ids
+}
+
+func filterExcludedProjects(events []Event, excluded map[string]bool) []Event {
+	if len(excluded) == 0 {
+		return events
+	}
+	var result []Event
+	for _, event := range events {
+		if !excluded[event.ProjectID] {
+			result = append(result, event)
+		}
+	}
+	return result
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+)
+
+// execError は --exec のコマンドが失敗した場合のエラー
+type execError struct {
+	command string
+	err     error
+}
+
+func (e *execError) Error() string {
+	return fmt.Sprintf("exec %q error: %v", e.command, e.err)
+}
+
+func (e *execError) Unwrap() error {
+	return e.err
+}
+
+// execHook は --exec のコマンドに環境変数で渡すレポートの情報
+type execHook struct {
+	command string
+	total   int
+	period  period
+	project string
+	format  string
+	// --output で書き込んだファイル。標準出力に出力した場合は空
+	output string
+}
+
+// レポートを書き込んだ後に、レポートを標準入力に渡してシェルでコマンドを実行する
+// コマンドの標準出力・標準エラー出力はそのまま出力し、0以外で終了した場合はエラーにする
+func runExecHook(ctx context.Context, hook execHook, report []byte) error {
+	name, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		name, flag = "cmd", "/C"
+	}
+	cmd := exec.CommandContext(ctx, name, flag, hook.command)
+	cmd.Stdin = bytes.NewReader(report)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"TODOIST_REPORT_TOTAL="+strconv.Itoa(hook.total),
+		"TODOIST_REPORT_PERIOD="+hook.period.label(),
+		"TODOIST_REPORT_SINCE="+hook.period.since.Format(dateLayout),
+		"TODOIST_REPORT_UNTIL="+hook.period.until.Format(dateLayout),
+		"TODOIST_REPORT_PROJECT="+hook.project,
+		"TODOIST_REPORT_FORMAT="+hook.format,
+		"TODOIST_REPORT_OUTPUT="+hook.output,
+	)
+	if err := cmd.Run(); err != nil {
+		// Ctrl-C で中断した場合はコマンドの失敗ではなく中断として扱う
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &execError{command: hook.command, err: err}
+	}
+	return nil
+}
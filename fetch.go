@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"todoistreport/todoist"
+)
+
+const defaultConcurrency = 4
+
+type pageRequest struct {
+	Project todoist.Project
+	Page    int
+}
+
+type pageResult struct {
+	Project  todoist.Project
+	Page     int
+	Response todoist.GetActivityLogResponse
+}
+
+// 各プロジェクトの startPage..endPage のページ要求を、プロジェクト順・ページ順に並べて返す
+func pageRequests(projects []todoist.Project, startPage, endPage int) []pageRequest {
+	var requests []pageRequest
+	for _, project := range projects {
+		for page := startPage; page <= endPage; page++ {
+			requests = append(requests, pageRequest{Project: project, Page: page})
+		}
+	}
+	return requests
+}
+
+// requests を最大 concurrency 並列で取得する。結果は requests と同じ順番で返す
+// いずれかの取得でエラーになった場合は残りの取得をキャンセルし、最初のエラーを返す
+// cache が nil でなければ、キャッシュできるページはキャッシュから読み込み、取得したものはキャッシュに保存する
+// progress が nil でなければ、1ページ取得するたびに進み具合を表示する
+func fetchPages(ctx context.Context, client *todoist.Client, requests []pageRequest, eventTypes []string, concurrency int, cache *activityCache, apiToken string, progress *progress) ([]pageResult, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	defer progress.finish()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]pageResult, len(requests))
+	jobs := make(chan int)
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				request := requests[i]
+				response, err := fetchPage(ctx, client, request, eventTypes, cache, apiToken)
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+				results[i] = pageResult{Project: request.Project, Page: request.Page, Response: response}
+				progress.add(len(response.Events))
+			}
+		}()
+	}
+
+loop:
+	for i := range requests {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	// 呼び出し元の ctx がキャンセルされた場合
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// キャッシュの読み書きに失敗してもレポートは出力できるので、警告だけ出して API から取得する
+func fetchPage(ctx context.Context, client *todoist.Client, request pageRequest, eventTypes []string, cache *activityCache, apiToken string) (todoist.GetActivityLogResponse, error) {
+	path, cacheable := cache.path(apiToken, request, eventTypes)
+	if cacheable {
+		response, ok, err := cache.get(path)
+		if err != nil {
+			log.Println(err)
+		}
+		if ok {
+			return response, nil
+		}
+	}
+
+	response, err := client.ActivityLogAll(ctx, todoist.ActivityLogOptions{
+		ProjectID:  request.Project.ID,
+		Page:       request.Page,
+		EventTypes: eventTypes,
+	})
+	if err != nil {
+		return todoist.GetActivityLogResponse{}, err
+	}
+
+	if cacheable {
+		if err := cache.put(path, response); err != nil {
+			log.Println(err)
+		}
+	}
+	return response, nil
+}
+
+// 週ごとのページは取得する範囲が重なることがあるので、同じイベントが複数のページに含まれる場合は最初のものだけを残す
+func dedupePageResults(results []pageResult) []pageResult {
+	seen := make(map[uint64]struct{})
+	for i := range results {
+		events := results[i].Response.Events[:0]
+		for _, event := range results[i].Response.Events {
+			if _, ok := seen[event.ID]; ok {
+				continue
+			}
+			seen[event.ID] = struct{}{}
+			events = append(events, event)
+		}
+		results[i].Response.Events = events
+	}
+	return results
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	"todoistreport/todoist"
+)
+
+func TestDedupePageResults(t *testing.T) {
+	results := []pageResult{
+		{Page: 1, Response: todoist.GetActivityLogResponse{Events: []todoist.ActivityEvent{{ID: 1}, {ID: 2}}}},
+		{Page: 2, Response: todoist.GetActivityLogResponse{Events: []todoist.ActivityEvent{{ID: 2}, {ID: 3}}}},
+	}
+
+	counts := make(map[uint64]int)
+	for _, result := range dedupePageResults(results) {
+		for _, event := range result.Response.Events {
+			counts[event.ID]++
+		}
+	}
+	for _, id := range []uint64{1, 2, 3} {
+		if counts[id] != 1 {
+			t.Errorf("event %d appears %d times, want 1", id, counts[id])
+		}
+	}
+	if got := results[0].Response.Events; len(got) != 2 {
+		t.Errorf("first page events = %+v, want the shared event kept in the first page", got)
+	}
+}
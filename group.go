@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	groupByNone    = ""
+	groupByWeek    = "week"
+	groupByDay     = "day"
+	groupByProject = "project"
+	groupByMonth   = "month"
+)
+
+var groupByValues = []string{groupByMonth, groupByWeek, groupByDay, groupByProject}
+
+// --group-by project のプロジェクトの並び順
+const (
+	groupSortName  = "name"
+	groupSortCount = "count"
+)
+
+func validateGroupSort(sortBy string) error {
+	switch sortBy {
+	case groupSortName, groupSortCount:
+		return nil
+	default:
+		return fmt.Errorf("unknown sort %q (available: %s, %s)", sortBy, groupSortName, groupSortCount)
+	}
+}
+
+// --week-start の値
+const (
+	weekStartMonday = "monday"
+	weekStartSunday = "sunday"
+)
+
+// 週の始まりの曜日にする。デフォルトは ISO週と同じ月曜
+func parseWeekStart(s string) (time.Weekday, error) {
+	switch s {
+	case weekStartMonday:
+		return time.Monday, nil
+	case weekStartSunday:
+		return time.Sunday, nil
+	default:
+		return time.Monday, fmt.Errorf("unknown week-start %q (available: %s, %s)", s, weekStartMonday, weekStartSunday)
+	}
+}
+
+// t が週の始まりから何日目か（0 始まり）
+func weekdayOffset(t time.Time, weekStart time.Weekday) int {
+	return (int(t.Weekday()) - int(weekStart) + 7) % 7
+}
+
+func validateGroupBy(groupBy string) error {
+	if groupBy == groupByNone {
+		return nil
+	}
+	for _, v := range groupByValues {
+		if groupBy == v {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown group-by %q (available: %s)", groupBy, strings.Join(groupByValues, ", "))
+}
+
+type eventGroup struct {
+	key    string
+	title  string
+	events []Event
+}
+
+// イベントを月・週・日ごとにまとめる。日付はイベントのタイムゾーンで判定する
+// 週は weekStart の曜日の0時から始まる
+// グループ・グループ内のイベントは元のイベントの順番（--order）のままにする
+func groupEvents(events []Event, groupBy string, weekStart time.Weekday) []eventGroup {
+	var groups []eventGroup
+	index := make(map[string]int)
+	for _, event := range events {
+		key, title := groupKey(event.Date, groupBy, weekStart)
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, eventGroup{key: key, title: title})
+		}
+		groups[i].events = append(groups[i].events, event)
+	}
+	return groups
+}
+
+func groupKey(t time.Time, groupBy string, weekStart time.Weekday) (key, title string) {
+	switch groupBy {
+	case groupByWeek:
+		// イベントのタイムゾーンの0時を週の境目にする
+		start := time.Date(t.Year(), t.Month(), t.Day()-weekdayOffset(t, weekStart), 0, 0, 0, 0, t.Location())
+		end := start.AddDate(0, 0, 6)
+		period := fmt.Sprintf("%s - %s", start.Format(dateLayout), end.Format(dateLayout))
+		// ISO週は月曜始まりなので、日曜始まりの場合は週番号を付けない
+		if weekStart != time.Monday {
+			key = start.Format(dateLayout)
+			return key, period
+		}
+		year, week := t.ISOWeek()
+		key = fmt.Sprintf("%04d-W%02d", year, week)
+		return key, fmt.Sprintf("%s (%s)", key, period)
+	case groupByMonth:
+		key = t.Format(monthLayout)
+		return key, key
+	default:
+		key = t.Format(dateLayout)
+		return key, key
+	}
+}
+
+// イベントを月ごとにまとめて、見出しに月ごとの小計を付ける
+func monthGroups(events []Event) []eventGroup {
+	groups := groupEvents(events, groupByMonth, time.Monday)
+	for i := range groups {
+		groups[i].title = fmt.Sprintf("%s (%d tasks)", groups[i].key, len(groups[i].events))
+	}
+	return groups
+}
+
+// イベントをプロジェクトごとにまとめて、見出しに件数を付ける
+// プロジェクトは sortBy が name なら名前順、count なら件数の多い順（同じ件数なら名前順）に並べる
+// showEmpty の場合は、projects のうち期間中に1件も無いプロジェクトも含める
+func projectGroups(events []Event, projects []string, sortBy string, showEmpty bool) []eventGroup {
+	var groups []eventGroup
+	index := make(map[string]int)
+	add := func(project string) int {
+		i, ok := index[project]
+		if !ok {
+			i = len(groups)
+			index[project] = i
+			groups = append(groups, eventGroup{key: project})
+		}
+		return i
+	}
+	for _, event := range events {
+		i := add(event.Project)
+		groups[i].events = append(groups[i].events, event)
+	}
+	if showEmpty {
+		for _, project := range projects {
+			add(project)
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if sortBy == groupSortCount && len(groups[i].events) != len(groups[j].events) {
+			return len(groups[i].events) > len(groups[j].events)
+		}
+		return groups[i].key < groups[j].key
+	})
+	for i := range groups {
+		groups[i].title = fmt.Sprintf("%s (%d tasks)", groups[i].key, len(groups[i].events))
+	}
+	return groups
+}
+
+// --group-by project, month の最後に全てのグループの合計を出力する
+func renderGrandTotal(w io.Writer, total int, format string) error {
+	var line string
+	switch format {
+	case formatMarkdown:
+		line = fmt.Sprintf("\n**Total: %d tasks**\n", total)
+	default:
+		line = fmt.Sprintf("\nTotal: %d tasks\n", total)
+	}
+	if _, err := io.WriteString(w, line); err != nil {
+		return fmt.Errorf("write error: %w", err)
+	}
+	return nil
+}
+
+// グループごとに見出しを出力し、その下に render でイベントを出力する
+func renderGroups(w io.Writer, groups []eventGroup, format string, render func(io.Writer, []Event) error) error {
+	for i, group := range groups {
+		var header string
+		switch format {
+		case formatMarkdown:
+			header = fmt.Sprintf("## %s\n\n", group.title)
+		default:
+			header = fmt.Sprintf("== %s ==\n", group.title)
+		}
+		if i > 0 {
+			header = "\n" + header
+		}
+		if _, err := io.WriteString(w, header); err != nil {
+			return fmt.Errorf("write error: %w", err)
+		}
+		if err := render(w, group.events); err != nil {
+			return err
+		}
+	}
+	return nil
+}
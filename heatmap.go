@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ヒートマップの濃さ。0件と、最大の件数を4段階に分けた範囲
+var (
+	heatmapShades      = []string{"··", "░░", "▒▒", "▓▓", "██"}
+	heatmapASCIIShades = []string{"..", "--", "++", "**", "##"}
+)
+
+// 曜日の見出しの幅と、1日（1マス）の幅
+const (
+	heatmapLabelWidth = 4
+	heatmapCellWidth  = 3
+	// 端末の幅がわからない場合の幅
+	defaultTerminalWidth = 80
+)
+
+var heatmapWeekdays = []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+
+// 濃さごとの ANSI の色（256色の緑）。0件は灰色にする
+var heatmapColors = []string{"\x1b[38;5;240m", "\x1b[38;5;22m", "\x1b[38;5;28m", "\x1b[38;5;34m", "\x1b[38;5;40m"}
+
+const ansiReset = "\x1b[0m"
+
+// heatmapOptions は --format heatmap の表示方法
+type heatmapOptions struct {
+	// 色を付けない場合は、ブロック文字の代わりに ASCII で出力する
+	color bool
+	// 1行の最大の幅。週が収まらない場合は折り返す
+	width int
+	// この日時より後の日は空白にする
+	now time.Time
+	// 一番上の行の曜日
+	weekStart time.Weekday
+}
+
+// 件数を 0〜4 の濃さにする。1件以上は max を4等分した範囲のどこに入るかで決める
+func heatmapLevel(count, max int) int {
+	if count <= 0 || max <= 0 {
+		return 0
+	}
+	return (count*4 + max - 1) / max
+}
+
+// 濃さ level（1〜4）になる件数の範囲。該当する件数が無い場合は ok が false
+func heatmapRange(level, max int) (from, to int, ok bool) {
+	from = (level-1)*max/4 + 1
+	to = level * max / 4
+	return from, to, from <= to
+}
+
+// GitHub の contributions のように、行を曜日・列を週にして日ごとの完了数の濃さを出力する
+// 期間外の日と opts.now より後の日は空白にする
+func renderHeatmap(w io.Writer, r report, opts heatmapOptions) error {
+	shades := heatmapASCIIShades
+	if opts.color {
+		shades = make([]string, len(heatmapShades))
+		for i, shade := range heatmapShades {
+			shades[i] = heatmapColors[i] + shade + ansiReset
+		}
+	}
+
+	counts := countByDay(r.Events, r.period)
+	max := 0
+	for _, c := range counts {
+		if c.Count > max {
+			max = c.Count
+		}
+	}
+
+	// 週（weekStart の曜日始まり）ごとに、曜日の位置に件数を入れる。-1 は空白
+	var weeks [][7]int
+	var headers []string
+	for _, c := range counts {
+		weekday := weekdayOffset(c.Date, opts.weekStart)
+		if len(weeks) == 0 || weekday == 0 {
+			weeks = append(weeks, [7]int{-1, -1, -1, -1, -1, -1, -1})
+			headers = append(headers, strconv.Itoa(c.Date.Day()))
+		}
+		if c.Date.After(opts.now) {
+			continue
+		}
+		weeks[len(weeks)-1][weekday] = c.Count
+	}
+
+	perLine := (opts.width - heatmapLabelWidth) / heatmapCellWidth
+	if perLine < 1 {
+		perLine = 1
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(r.headline() + "\n")
+	for start := 0; start < len(weeks); start += perLine {
+		end := start + perLine
+		if end > len(weeks) {
+			end = len(weeks)
+		}
+
+		// 列の見出しはその週の最初の日
+		fmt.Fprintf(&buf, "\n%*s", heatmapLabelWidth, "")
+		for _, header := range headers[start:end] {
+			fmt.Fprintf(&buf, "%-*s", heatmapCellWidth, header)
+		}
+		trimTrailingSpaces(&buf)
+		buf.WriteString("\n")
+		for weekday := 0; weekday < 7; weekday++ {
+			label := heatmapWeekdays[(int(opts.weekStart)+weekday)%7]
+			fmt.Fprintf(&buf, "%-*s", heatmapLabelWidth, label)
+			for _, week := range weeks[start:end] {
+				cell := "  "
+				if week[weekday] >= 0 {
+					cell = shades[heatmapLevel(week[weekday], max)]
+				}
+				buf.WriteString(cell + " ")
+			}
+			trimTrailingSpaces(&buf)
+			buf.WriteString("\n")
+		}
+	}
+
+	fmt.Fprintf(&buf, "\n%s 0", shades[0])
+	for level := 1; level < len(shades); level++ {
+		from, to, ok := heatmapRange(level, max)
+		if !ok {
+			continue
+		}
+		if from == to {
+			fmt.Fprintf(&buf, "  %s %d", shades[level], from)
+		} else {
+			fmt.Fprintf(&buf, "  %s %d-%d", shades[level], from, to)
+		}
+	}
+	buf.WriteString("\n")
+
+	if _, err := buf.WriteTo(w); err != nil {
+		return fmt.Errorf("write error: %w", err)
+	}
+	return nil
+}
+
+// 空白のマスで終わる行の末尾に空白を残さない
+func trimTrailingSpaces(buf *bytes.Buffer) {
+	buf.Truncate(len(bytes.TrimRight(buf.Bytes(), " ")))
+}
+
+// 端末の幅は COLUMNS 環境変数から読む。設定されていない場合は defaultTerminalWidth にする
+func terminalWidth() int {
+	width, err := strconv.Atoi(os.Getenv("COLUMNS"))
+	if err != nil || width <= 0 {
+		return defaultTerminalWidth
+	}
+	return width
+}
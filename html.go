@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+)
+
+// htmlReport は --format html で出力するページの内容
+type htmlReport struct {
+	report
+	Daily []dailyCount
+	Max   int
+}
+
+func newHTMLReport(r report) htmlReport {
+	h := htmlReport{
+		report: r,
+		Daily:  countByDay(r.allEvents, r.period),
+	}
+	for _, c := range h.Daily {
+		if c.Count > h.Max {
+			h.Max = c.Count
+		}
+	}
+	return h
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"formatDate": func(e Event) string { return e.Date.Format(reportDateLayout) },
+	"formatDay":  func(c dailyCount) string { return c.Date.Format(dateLayout) },
+	// 日ごとの完了数を最大値に対する割合(%)にして棒の長さにする
+	"barWidth": func(count, max int) int {
+		if max == 0 {
+			return 0
+		}
+		return count * 100 / max
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="ja">
+<head>
+<meta charset="utf-8">
+<title>{{.Project}} {{.Period}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", "Hiragino Sans", sans-serif; margin: 2em auto; max-width: 960px; color: #333; }
+h1 { font-size: 1.5em; border-bottom: 2px solid #e44332; padding-bottom: .3em; }
+h2 { font-size: 1.2em; margin-top: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ddd; padding: .4em .6em; text-align: left; }
+th { background: #f7f7f7; }
+.date { white-space: nowrap; color: #666; }
+.daily { list-style: none; padding: 0; }
+.daily li { display: flex; align-items: center; margin: .15em 0; }
+.daily .day { width: 7em; color: #666; }
+.daily .bar { background: #e44332; height: .8em; margin-right: .5em; }
+</style>
+</head>
+<body>
+<h1>{{.Project}} {{.Period}}</h1>
+<p>{{.Total}} tasks</p>
+<table>
+<thead><tr><th>Date</th><th>Task</th><th>Project</th></tr></thead>
+<tbody>
+{{- range .Events}}
+<tr><td class="date">{{formatDate .}}</td><td>{{.Content}}</td><td>{{.Project}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+<h2>Daily</h2>
+<ul class="daily">
+{{- $max := .Max}}
+{{- range .Daily}}
+<li><span class="day">{{formatDay .}}</span><span class="bar" style="width: {{barWidth .Count $max}}%"></span>{{.Count}}</li>
+{{- end}}
+</ul>
+</body>
+</html>
+`))
+
+func renderHTML(w io.Writer, h htmlReport) error {
+	if err := htmlTemplate.Execute(w, h); err != nil {
+		return fmt.Errorf("html template execute error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// --limit で一覧を切り詰めても、日ごとの完了数は Total と同じく全てのイベントで数える
+func TestNewHTMLReportDailyIgnoresLimit(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	p := monthPeriod(time.Date(2024, 3, 1, 0, 0, 0, 0, loc))
+	events := []Event{
+		{ID: "1", Date: time.Date(2024, 3, 1, 9, 0, 0, 0, loc)},
+		{ID: "2", Date: time.Date(2024, 3, 2, 9, 0, 0, 0, loc)},
+		{ID: "3", Date: time.Date(2024, 3, 2, 10, 0, 0, 0, loc)},
+	}
+	r := newReport("仕事", p, events).limit(1, orderAsc)
+
+	h := newHTMLReport(r)
+	total := 0
+	for _, c := range h.Daily {
+		total += c.Count
+	}
+	if total != r.Total {
+		t.Errorf("sum of daily counts = %d, want Total %d", total, r.Total)
+	}
+	if len(h.Events) != 1 {
+		t.Errorf("len(Events) = %d, want 1", len(h.Events))
+	}
+}
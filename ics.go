@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const icsTimeLayout = "20060102T150405Z"
+
+// 完了したタスクごとに VEVENT を1つ持つ VCALENDAR を出力する（RFC 5545）
+// UID はイベント ID から作るので、何度出力しても同じタスクは同じ UID になる
+func renderICS(w io.Writer, r report) error {
+	var buf strings.Builder
+	writeLine := func(line string) {
+		buf.WriteString(foldICSLine(line))
+		buf.WriteString("\r\n")
+	}
+
+	writeLine("BEGIN:VCALENDAR")
+	writeLine("VERSION:2.0")
+	writeLine("PRODID:-//kyokomi//todoistreport//EN")
+	writeLine("CALSCALE:GREGORIAN")
+	writeLine("X-WR-CALNAME:" + escapeICSText(r.headline()))
+	for _, event := range r.Events {
+		at := event.Date.UTC().Format(icsTimeLayout)
+		writeLine("BEGIN:VEVENT")
+		writeLine(fmt.Sprintf("UID:todoist-activity-%s@todoistreport", event.ID))
+		writeLine("DTSTAMP:" + at)
+		writeLine("DTSTART:" + at)
+		writeLine("DTEND:" + at)
+		writeLine("SUMMARY:" + escapeICSText(event.Content))
+		if event.Project != "" {
+			writeLine("CATEGORIES:" + escapeICSText(event.Project))
+		}
+		writeLine("END:VEVENT")
+	}
+	writeLine("END:VCALENDAR")
+
+	if _, err := io.WriteString(w, buf.String()); err != nil {
+		return fmt.Errorf("write error: %w", err)
+	}
+	return nil
+}
+
+func escapeICSText(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, ";", "\\;")
+	s = strings.ReplaceAll(s, ",", "\\,")
+	s = strings.ReplaceAll(s, "\r\n", "\\n")
+	s = strings.ReplaceAll(s, "\n", "\\n")
+	return s
+}
+
+// 1行は75オクテットまでなので、超える場合は CRLF + 空白で折り返す。マルチバイト文字の途中では折り返さない
+func foldICSLine(line string) string {
+	const limit = 75
+	if len(line) <= limit {
+		return line
+	}
+
+	var buf strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > limit {
+			buf.WriteString("\r\n ")
+			width = 1 // 先頭の空白の分
+		}
+		buf.WriteRune(r)
+		width += size
+	}
+	return buf.String()
+}
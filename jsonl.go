@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"time"
+)
+
+// --format jsonl をページを取得するごとに出力できるか
+// 全てのイベントが必要な --limit と、書き込んだレポートを後で使う --output, --exec, --slack-webhook の場合はまとめて出力する
+// 完了済みタスク API（--api rest）は週のページに分かれていないので、まとめて出力する
+func canStreamJSONL(cfg config, outputFile string) bool {
+	return cfg.format == formatJSONL && !cfg.countOnly && cfg.limit == 0 && cfg.api == apiSync &&
+		cfg.templateFile == "" && outputFile == "" && cfg.exec == "" && cfg.slackWebhook == ""
+}
+
+// アクティビティログを1週間分のページずつ取得して、取得するごとに w に jsonl で書き込み、書き込んだ件数を返す
+// 並べ替えを週の中だけで済ませるために、古いページから（--order desc では新しいページから）順に取得する
+// ページの範囲は隣のページと重なることがあるので、次のページにも含まれるかもしれない週のイベントは次のページと一緒に出力する
+func streamJSONL(ctx context.Context, w io.Writer, cfg config, accounts []resolvedAccount, scope fetchScope, now time.Time, debug *log.Logger) (int, error) {
+	// コメントはページごとではなく、アカウントごとに1回だけ取得する
+	itemNotes := make([]map[string][]string, len(accounts))
+	if scope.withNotes {
+		for i, ra := range accounts {
+			notes, err := fetchItemNotes(ctx, ra.client)
+			if err != nil {
+				return 0, err
+			}
+			itemNotes[i] = notes
+		}
+	}
+
+	pageCfg := cfg
+	// 1ページずつ取得するので、ページ数の進み具合は表示しない
+	pageCfg.quiet = true
+	weekStart := startOfWeek(now, scope.loc)
+
+	total := 0
+	emit := func(events []Event) error {
+		if cfg.redact {
+			events = redactContents(events)
+		}
+		if !cfg.showID {
+			events = withoutIDs(events)
+		}
+		total += len(events)
+		return renderJSONL(w, events)
+	}
+
+	seen := make(map[string]bool)
+	var pending []Event
+	for _, page := range streamPages(scope.startPage, scope.endPage, cfg.order) {
+		pageScope := scope
+		pageScope.startPage, pageScope.endPage = page, page
+		pageScope.withNotes = false
+		for i, ra := range accounts {
+			events, err := fetchAccountEvents(ctx, pageCfg, ra, pageScope, debug)
+			if err != nil {
+				return total, err
+			}
+			if itemNotes[i] != nil {
+				events = attachNotes(events, itemNotes[i])
+			}
+			for _, event := range events {
+				// 同じイベントが重なったページの両方に含まれる場合は最初のものだけにする
+				key := ra.label + "/" + event.ID
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				pending = append(pending, event)
+			}
+		}
+		sortEvents(pending, cfg.order)
+
+		// このページの週のイベントは次のページにも含まれるかもしれないので、その先のイベントだけを出力する
+		n := 0
+		if cfg.order == orderDesc {
+			boundary := weekStart.AddDate(0, 0, -7*(page-1))
+			for n < len(pending) && !pending[n].Date.Before(boundary) {
+				n++
+			}
+		} else {
+			boundary := weekStart.AddDate(0, 0, -7*page)
+			for n < len(pending) && pending[n].Date.Before(boundary) {
+				n++
+			}
+		}
+		if err := emit(pending[:n]); err != nil {
+			return total, err
+		}
+		pending = append([]Event(nil), pending[n:]...)
+	}
+	if err := emit(pending); err != nil {
+		return total, err
+	}
+	return total, nil
+}
+
+// 取得するページの順番。ページは今週が0なので、古い順（asc）は endPage から startPage に向かって取得する
+func streamPages(startPage, endPage int, order string) []int {
+	pages := make([]int, 0, endPage-startPage+1)
+	for page := startPage; page <= endPage; page++ {
+		if order == orderDesc {
+			pages = append(pages, page)
+		} else {
+			pages = append(pages, endPage-(page-startPage))
+		}
+	}
+	return pages
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"todoistreport/todoist"
+)
+
+func TestStreamJSONL(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	// 2024/03/20 は水曜日なので、0ページ目は 2024/03/18 の週、2ページ目は 2024/03/04 の週
+	now := time.Date(2024, 3, 20, 12, 0, 0, 0, loc)
+	event := func(id int, date string) string {
+		return fmt.Sprintf(`{"id":%d,"object_type":"item","object_id":"%d","event_type":"completed","event_date":"%s","parent_project_id":"1","extra_data":{"content":"task %d"}}`, id, id, date, id)
+	}
+	pages := map[string][]string{
+		// 3 は 1ページ目の週のイベントだが、範囲が重なって2ページ目にも含まれる
+		"2": {event(1, "2024-03-05T00:00:00Z"), event(3, "2024-03-11T01:00:00Z")},
+		"1": {event(3, "2024-03-11T01:00:00Z"), event(2, "2024-03-12T00:00:00Z")},
+		"0": {event(4, "2024-03-18T00:00:00Z")},
+	}
+
+	tests := []struct {
+		order   string
+		wantIDs []string
+	}{
+		{order: orderAsc, wantIDs: []string{"1", "3", "2", "4"}},
+		{order: orderDesc, wantIDs: []string{"4", "2", "3", "1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.order, func(t *testing.T) {
+			var out bytes.Buffer
+			// 最後に取得するページを取得する時点で、既に出力を始めていること
+			lastPage := "0"
+			if tt.order == orderDesc {
+				lastPage = "2"
+			}
+			writtenBeforeLastPage := -1
+			ra := newStubAccount(nil)
+			ra.client = todoist.NewClient("test-token", todoist.WithMaxRetries(0), todoist.WithTransport(stubTransport(func(req *http.Request) (*http.Response, error) {
+				page := req.URL.Query().Get("page")
+				if page == lastPage {
+					writtenBeforeLastPage = strings.Count(out.String(), "\n")
+				}
+				events := pages[page]
+				body := fmt.Sprintf(`{"events":[%s],"count":%d}`, strings.Join(events, ","), len(events))
+				return stubAPI(map[string]string{req.URL.Path: body})(req)
+			})))
+			cfg := testConfig()
+			cfg.format = formatJSONL
+			cfg.order = tt.order
+			cfg.showID = true
+			cfg.concurrency = 1
+			scope := fetchScope{
+				period:    period{since: time.Date(2024, 3, 4, 0, 0, 0, 0, loc), until: now},
+				startPage: 0,
+				endPage:   2,
+				loc:       loc,
+			}
+
+			total, err := streamJSONL(context.Background(), &out, cfg, []resolvedAccount{ra}, scope, now, log.New(io.Discard, "", 0))
+			if err != nil {
+				t.Fatalf("streamJSONL() error = %v", err)
+			}
+			if total != len(tt.wantIDs) {
+				t.Errorf("total = %d, want %d", total, len(tt.wantIDs))
+			}
+			var ids []string
+			dec := json.NewDecoder(&out)
+			for dec.More() {
+				var e Event
+				if err := dec.Decode(&e); err != nil {
+					t.Fatalf("decode error = %v", err)
+				}
+				ids = append(ids, e.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.wantIDs, ",") {
+				t.Errorf("ids = %v, want %v", ids, tt.wantIDs)
+			}
+			if writtenBeforeLastPage < 1 {
+				t.Errorf("lines written before the last page = %d, want 1 or more", writtenBeforeLastPage)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"todoistreport/todoist"
+)
+
+// ラベルの情報が取得できなかったイベントの集計先
+const unknownLabel = "(unknown)"
+
+type labelCount struct {
+	Label string
+	Count int
+}
+
+// アクティビティログの extra_data にはラベルが含まれないため、未完了のタスク（items）に
+// 残っているもの（繰り返しタスクなど）からしかラベルを知ることができない
+// ラベルが分からないイベントは unknownLabel として数え、推測で割り当てることはしない
+func fetchItemLabels(ctx context.Context, client *todoist.Client) (map[string][]string, []todoist.Label, error) {
+	items, err := client.Items(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get items error: %w", err)
+	}
+	labels, err := client.Labels(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get labels error: %w", err)
+	}
+
+	itemLabels := make(map[string][]string, len(items.Items))
+	for _, item := range items.Items {
+		itemLabels[item.ID] = item.Labels
+	}
+
+	var active []todoist.Label
+	for _, label := range labels.Labels {
+		if !label.IsDeleted {
+			active = append(active, label)
+		}
+	}
+	return itemLabels, active, nil
+}
+
+// ラベルごとの完了数を集計する。複数のラベルが付いたタスクはそれぞれのラベルで数える
+// 完了数が0のラベルも含め、完了数の多い順に並べる
+func countByLabel(events []Event, itemLabels map[string][]string, labels []todoist.Label) []labelCount {
+	counts := make(map[string]int)
+	for _, label := range labels {
+		counts[label.Name] = 0
+	}
+	for _, event := range events {
+		names, ok := itemLabels[event.TaskID]
+		if !ok {
+			counts[unknownLabel]++
+			continue
+		}
+		for _, name := range names {
+			counts[name]++
+		}
+	}
+
+	result := make([]labelCount, 0, len(counts))
+	for label, count := range counts {
+		result = append(result, labelCount{Label: label, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Label < result[j].Label
+	})
+	return result
+}
+
+func renderLabelSummary(w io.Writer, counts []labelCount, format string) error {
+	var buf bytes.Buffer
+	switch format {
+	case formatMarkdown:
+		buf.WriteString("\n| Label | Count |\n| --- | --- |\n")
+		for _, c := range counts {
+			fmt.Fprintf(&buf, "| %s | %d |\n", escapeMarkdownCell(c.Label), c.Count)
+		}
+	default:
+		buf.WriteString("\n")
+		for _, c := range counts {
+			fmt.Fprintf(&buf, "%s %d\n", c.Label, c.Count)
+		}
+	}
+
+	if _, err := buf.WriteTo(w); err != nil {
+		return fmt.Errorf("write error: %w", err)
+	}
+	return nil
+}
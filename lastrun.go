@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"todoistreport/todoist"
+)
+
+// lastRunState は --since-last-run で使う、プロジェクトごとの最後に成功した実行の日時
+// キャッシュではないので --no-cache を指定しても読み書きする
+type lastRunState struct {
+	path string
+}
+
+func newLastRunState() (*lastRunState, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return &lastRunState{path: filepath.Join(dir, "last_run.json")}, nil
+}
+
+// プロジェクト ID をキーにする。アカウント全体を対象にした場合はプロジェクト ID が無いので、トークンのハッシュをキーにする
+func lastRunKey(apiToken string, project todoist.Project) string {
+	if project.ID == "" {
+		return "account-" + tokenHash(apiToken)
+	}
+	return project.ID
+}
+
+func (s *lastRunState) load() (map[string]time.Time, error) {
+	markers := make(map[string]time.Time)
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return markers, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("last run state read error: %w", err)
+	}
+	if err := json.Unmarshal(data, &markers); err != nil {
+		return nil, fmt.Errorf("last run state json unmarshall error: %w", err)
+	}
+	return markers, nil
+}
+
+// accounts の全てのプロジェクトの最後に実行した日時を ranAt にする。他のプロジェクトの日時はそのまま残す
+func (s *lastRunState) save(accounts []resolvedAccount, ranAt time.Time) error {
+	markers, err := s.load()
+	if err != nil {
+		// 壊れたファイルは作り直す
+		markers = make(map[string]time.Time)
+	}
+	for _, ra := range accounts {
+		for _, project := range ra.projects {
+			markers[lastRunKey(ra.token, project)] = ranAt
+		}
+	}
+
+	data, err := json.Marshal(markers)
+	if err != nil {
+		return fmt.Errorf("last run state json marshal error: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("last run state dir create error: %w", err)
+	}
+	if err := os.WriteFile(s.path, data, 0o600); err != nil {
+		return fmt.Errorf("last run state write error: %w", err)
+	}
+	return nil
+}
+
+// 取得する期間の開始日時。最も古い前回の実行日時にする
+// 一度も実行していないプロジェクトがある場合は、--since, --target の期間の開始日時 fallback も含める
+func lastRunSince(markers map[string]time.Time, accounts []resolvedAccount, fallback time.Time) time.Time {
+	var since time.Time
+	for _, ra := range accounts {
+		for _, project := range ra.projects {
+			t, ok := markers[lastRunKey(ra.token, project)]
+			if !ok {
+				t = fallback
+			}
+			if since.IsZero() || t.Before(since) {
+				since = t
+			}
+		}
+	}
+	if since.IsZero() {
+		return fallback
+	}
+	return since
+}
+
+// 前回の実行日時より前のイベントを取り除く。一度も実行していないプロジェクトのイベントはそのまま残す
+func filterLastRun(events []Event, ra resolvedAccount, markers map[string]time.Time) []Event {
+	marker := func(event Event) (time.Time, bool) {
+		for _, project := range ra.projects {
+			if project.ID == "" || project.ID == event.ProjectID {
+				t, ok := markers[lastRunKey(ra.token, project)]
+				return t, ok
+			}
+		}
+		return time.Time{}, false
+	}
+
+	var result []Event
+	for _, event := range events {
+		if t, ok := marker(event); ok && event.Date.Before(t) {
+			continue
+		}
+		result = append(result, event)
+	}
+	return result
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ログの出力形式
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+func validateLogFormat(format string) error {
+	switch format {
+	case logFormatText, logFormatJSON:
+		return nil
+	default:
+		return fmt.Errorf("unknown log format %q (available: %s, %s)", format, logFormatText, logFormatJSON)
+	}
+}
+
+// 標準のロガー（log.Println など）の出力形式を設定する
+// quiet の場合は警告などのログを出力しない。終了する原因になったエラーは newErrorLogger で出力する
+func setupLogger(format string, quiet bool) {
+	if quiet {
+		log.SetOutput(io.Discard)
+		return
+	}
+	if format == logFormatJSON {
+		log.SetFlags(0)
+		log.SetOutput(newJSONLogWriter(os.Stderr, "info"))
+		return
+	}
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+}
+
+// 終了する原因になったエラーを出力するロガー。JSON の場合は level を error にする
+// quiet の場合も出力するが、日時などは付けずにエラーのメッセージだけにする
+func newErrorLogger(format string, quiet bool) *log.Logger {
+	if format == logFormatJSON {
+		return log.New(newJSONLogWriter(os.Stderr, "error"), "", 0)
+	}
+	if quiet {
+		return log.New(os.Stderr, "", 0)
+	}
+	return log.Default()
+}
+
+// --verbose のときだけ標準エラー出力にデバッグログを出力する
+func newDebugLogger(verbose bool, format string) *log.Logger {
+	if !verbose {
+		return log.New(io.Discard, "", 0)
+	}
+	if format == logFormatJSON {
+		return log.New(newJSONLogWriter(os.Stderr, "debug"), "", 0)
+	}
+	return log.New(os.Stderr, "[debug] ", log.LstdFlags)
+}
+
+// jsonLogWriter は *log.Logger が書き込む1行のログを、level, msg, time を持つ JSON の1行に変換する
+// メッセージに含まれる page=1 project_id="123" のような key=value はフィールドとしても出力する
+// アクティビティログなどの URL はクライアントでトークンを伏せてからログに渡しているので、トークンは含まれない
+type jsonLogWriter struct {
+	mu    sync.Mutex
+	w     io.Writer
+	level string
+}
+
+func newJSONLogWriter(w io.Writer, level string) *jsonLogWriter {
+	return &jsonLogWriter{w: w, level: level}
+}
+
+func (w *jsonLogWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\n")
+	level := w.level
+	if strings.HasPrefix(msg, "warning: ") {
+		level = "warn"
+		msg = strings.TrimPrefix(msg, "warning: ")
+	}
+
+	entry := logFields(msg)
+	entry["time"] = time.Now().Format(time.RFC3339Nano)
+	entry["level"] = level
+	entry["msg"] = msg
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(entry); err != nil {
+		return 0, fmt.Errorf("log json encode error: %w", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := buf.WriteTo(w.w); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// msg から key=value（value は %q の形式でもよい）を取り出す
+func logFields(msg string) map[string]interface{} {
+	fields := make(map[string]interface{})
+	for rest := msg; rest != ""; {
+		var token string
+		token, rest = nextLogToken(rest)
+		key, value, ok := strings.Cut(token, "=")
+		if !ok || !isLogFieldKey(key) {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			fields[key] = unquoted
+		} else if n, err := strconv.Atoi(value); err == nil {
+			fields[key] = n
+		} else {
+			fields[key] = value
+		}
+	}
+	return fields
+}
+
+// フィールド名として扱うのは英小文字・数字・_ だけのもの（URL のクエリなどを誤って取り出さないようにする）
+func isLogFieldKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// 空白で区切った次のトークンを返す。"..." の中の空白では区切らない
+func nextLogToken(s string) (token, rest string) {
+	s = strings.TrimLeft(s, " ")
+	inQuote := false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if inQuote {
+				i++
+			}
+		case '"':
+			inQuote = !inQuote
+		case ' ':
+			if !inQuote {
+				return s[:i], s[i+1:]
+			}
+		}
+	}
+	return s, ""
+}
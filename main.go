@@ -1,207 +1,275 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
-	"net/http"
-	"net/url"
 	"os"
-	"strconv"
+	"os/signal"
+	"syscall"
 	"time"
-)
 
-func main() {
-	apiToken := flag.String("token", os.Getenv("TODOIST_API_TOKEN"), "todoist api token")
-	projectName := flag.String("project", "", "project name")
-	target := flag.String("target", time.Now().Format("2006/01"), "target YYYY/MM")
-	flag.Parse()
+	"todoistreport/todoist"
+)
 
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
+// 終了コード
+const (
+	exitCodeError           = 1
+	exitCodeAuthError       = 2
+	exitCodeProjectNotFound = 3
+	exitCodeNoEvents        = 4
+	exitCodeRateLimited     = 5
+	exitCodeNetworkError    = 6
+	exitCodeExecFailed      = 7
+	// シェルと同じく 128 + SIGINT(2)
+	exitCodeInterrupted = 130
+)
 
-	ctx := context.Background()
+type config struct {
+	accounts        []account
+	projectName     string
+	substring       bool
+	includeArchived bool
+	recursive       bool
+	sharing         sharingFilter
+	exclude         projectExcluder
+	target          string
+	compare         string
+	sinceDate       string
+	untilDate       string
+	sinceLastRun    bool
+	tz              string
+	eventTypes      string
+	initiator       string
+	api             string
+	format          string
+	fields          string
+	templateFile    string
+	maxContentWidth int
+	redact          bool
+	summary         bool
+	parseEstimates  bool
+	estimatePattern string
+	grep            string
+	caseSensitive   bool
+	labels          bool
+	top             int
+	topIgnoreCase   bool
+	clients         bool
+	completionRate  bool
+	showClient      bool
+	showID          bool
+	collapse        bool
+	rescheduled     bool
+	tree            bool
+	groupBy         string
+	weekStart       string
+	groupSort       string
+	showEmpty       bool
+	order           string
+	limit           int
+	output          string
+	tee             bool
+	timeout         time.Duration
+	proxy           string
+	baseURL         string
+	oauth           todoist.OAuthConfig
+	userAgent       string
+	concurrency     int
+	rps             float64
+	maxPages        int
+	retentionWeeks  int
+	retries         int
+	projectCacheTTL time.Duration
+	noCache         bool
+	verbose         bool
+	quiet           bool
+	color           string
+	noUnicode       bool
+	logFormat       string
+	dryRun          bool
+	raw             bool
+	failIfEmpty     bool
+	countOnly       bool
+	skipEmpty       bool
+	withNotes       bool
+	relative        bool
+	exec            string
+	slackWebhook    string
+
+	stdout io.Writer
+	// レポートの期間とページの範囲を計算する基準の現在時刻。nil の場合は time.Now を使う
+	now func() time.Time
+}
 
-	projectID, err := searchProjectByName(ctx, *apiToken, *projectName)
-	if err != nil {
-		log.Fatalln(err)
+func (cfg config) currentTime() time.Time {
+	if cfg.now != nil {
+		return cfg.now()
 	}
+	return time.Now()
+}
 
-	// todoistのアクティビティログは、今日を0ページ目として取得する必要があるため
-	// 指定した年/月が、何ページ目か何ページ目までなのかを計算する
-	targetDate, err := time.Parse("2006/01", *target)
-	if err != nil {
-		log.Fatalln(err)
-	}
+func main() {
+	cfg := config{stdout: os.Stdout, now: time.Now}
+	configPath := flag.String("config", defaultConfigPath(), "config file path (json with token, project, tz)")
+	var accounts accountsFlag
+	flag.Var(&accounts, "token", "todoist api token, optionally labeled as label=token. repeat for multiple accounts (default: config file, then $TODOIST_API_TOKEN)")
+	flag.StringVar(&cfg.projectName, "project", "", "project name or id (comma separated for multiple projects). empty means all projects. - reads one project per line from stdin and reports each")
+	flag.BoolVar(&cfg.substring, "substring", false, "match project names by case-insensitive substring")
+	flag.BoolVar(&cfg.includeArchived, "include-archived", false, "include archived projects in project name resolution")
+	flag.BoolVar(&cfg.recursive, "recursive", false, "include all sub-projects of the specified projects")
+	sharedOnly := flag.Bool("shared-only", false, "report only shared (team) projects")
+	personalOnly := flag.Bool("personal-only", false, "report only personal (not shared) projects")
+	excludePatterns := flag.String("exclude", "", "project names or glob patterns to exclude (comma separated, e.g. Inbox,Personal*)")
+	noInbox := flag.Bool("no-inbox", false, "exclude the inbox project")
+	flag.StringVar(&cfg.target, "target", "", "target YYYY/MM, or YYYY for the whole year (default: the current month)")
+	flag.StringVar(&cfg.compare, "compare", "", "compare the report with this month YYYY/MM (text, markdown only)")
+	flag.StringVar(&cfg.sinceDate, "since", "", "report start date YYYY/MM/DD (inclusive). overrides --target")
+	flag.StringVar(&cfg.untilDate, "until", "", "report end date YYYY/MM/DD (exclusive). defaults to now")
+	flag.BoolVar(&cfg.sinceLastRun, "since-last-run", false, "report only events since the last successful run of each project. the first run uses --since or --target")
+	flag.StringVar(&cfg.tz, "tz", "", "timezone for date filtering and display (e.g. Asia/Tokyo). defaults to system local")
+	flag.StringVar(&cfg.eventTypes, "event-type", "completed", "activity event types to report (comma separated, e.g. completed,added)")
+	flag.StringVar(&cfg.initiator, "initiator", "", "report only events by this user: me or a user id (useful for shared projects)")
+	flag.StringVar(&cfg.api, "api", apiSync, "data source: sync (activity log) or rest (completed tasks api, completed events only)")
+	flag.StringVar(&cfg.format, "format", formatText, "output format (text, json, jsonl, csv, markdown, ics, html, checklist, prometheus, heatmap, digest)")
+	flag.StringVar(&cfg.fields, "fields", "", "comma separated columns to output in order (date, content, project, event_type, account, client, last_due_date, due_date, task_id, id). text, csv, markdown only")
+	flag.StringVar(&cfg.templateFile, "template-file", "", "go text/template file applied to the report ({{.Project}}, {{.Period}}, {{.Total}}, {{range .Events}}...{{end}}) instead of the text listing")
+	flag.IntVar(&cfg.maxContentWidth, "max-content-width", 0, "truncate task contents to N characters with an ellipsis (0 means no limit). not applied to json, jsonl")
+	flag.BoolVar(&cfg.redact, "redact", false, "replace task contents with task #<id> to share reports without revealing task titles")
+	flag.BoolVar(&cfg.summary, "summary", false, "print completion count per day after the listing (text, markdown only). adds a per-day gauge with prometheus")
+	flag.BoolVar(&cfg.parseEstimates, "parse-estimates", false, "sum time estimates like (2h) or [30m] in task contents and show the total in the header")
+	flag.StringVar(&cfg.grep, "grep", "", "report only tasks whose content matches this regexp (case-insensitive)")
+	flag.BoolVar(&cfg.caseSensitive, "grep-case-sensitive", false, "make --grep case-sensitive")
+	flag.StringVar(&cfg.estimatePattern, "estimate-pattern", defaultEstimatePattern, "regexp for --parse-estimates. the first group must be a go duration (e.g. 1h30m)")
+	flag.IntVar(&cfg.top, "top", 0, "print the N most frequently completed task contents after the listing (text, markdown only)")
+	flag.BoolVar(&cfg.topIgnoreCase, "top-ignore-case", false, "ignore case when grouping task contents for --top")
+	flag.BoolVar(&cfg.labels, "labels", false, "print completion count per label after the listing (text, markdown only)")
+	flag.BoolVar(&cfg.completionRate, "completion-rate", false, "print the number of added and completed tasks and the completion rate after the listing (text, markdown only)")
+	flag.BoolVar(&cfg.clients, "clients", false, "print completion count per client (device) after the listing (text, markdown only)")
+	flag.BoolVar(&cfg.showClient, "show-client", false, "show the client (device) each task was completed from")
+	flag.BoolVar(&cfg.collapse, "collapse-recurring", false, "show tasks completed several times with the same content as one line with the count and date range (json keeps every event)")
+	flag.BoolVar(&cfg.showID, "show-id", false, "show the task id and the event id of each event")
+	flag.BoolVar(&cfg.rescheduled, "rescheduled", false, "report only completed tasks whose due date was changed, with the old and new due dates")
+	flag.BoolVar(&cfg.tree, "tree", false, "indent completed subtasks beneath their parent task (text only)")
+	flag.StringVar(&cfg.groupBy, "group-by", groupByNone, "group events under a header per month, week, day or project (text, markdown only)")
+	flag.StringVar(&cfg.groupSort, "sort", groupSortName, "order of projects for --group-by project: name or count")
+	flag.BoolVar(&cfg.showEmpty, "show-empty", false, "with --group-by project, also show projects with no events")
+	flag.StringVar(&cfg.weekStart, "week-start", weekStartMonday, "first day of the week for --group-by week and the heatmap format (monday, sunday)")
+	flag.StringVar(&cfg.order, "order", orderAsc, "sort order of events by date: asc or desc")
+	flag.IntVar(&cfg.limit, "limit", 0, "report only the N most recent events (0 means unlimited). the header still shows the total")
+	flag.StringVar(&cfg.output, "output", "", "write the report to this file instead of stdout. supports {{.Year}}, {{.Month}} templates")
+	flag.BoolVar(&cfg.tee, "tee", false, "with --output, also print the report to stdout")
+	flag.DurationVar(&cfg.timeout, "timeout", todoist.DefaultTimeout, "http request timeout")
+	flag.StringVar(&cfg.baseURL, "base-url", todoist.DefaultBaseURL, "base url of the todoist api (scheme and host)")
+	flag.StringVar(&cfg.oauth.ClientID, "oauth-client-id", "", "oauth client id used to refresh an expired --token access token")
+	flag.StringVar(&cfg.oauth.ClientSecret, "oauth-client-secret", "", "oauth client secret used to refresh an expired --token access token (default: $TODOIST_OAUTH_CLIENT_SECRET)")
+	flag.StringVar(&cfg.oauth.RefreshToken, "oauth-refresh-token", "", "oauth refresh token used to refresh an expired --token access token (default: $TODOIST_OAUTH_REFRESH_TOKEN)")
+	flag.StringVar(&cfg.proxy, "proxy", "", "proxy url for api requests. overrides HTTPS_PROXY, HTTP_PROXY and NO_PROXY")
+	flag.StringVar(&cfg.userAgent, "user-agent", defaultUserAgent(), "User-Agent header sent with api requests")
+	flag.IntVar(&cfg.concurrency, "concurrency", defaultConcurrency, "number of activity pages fetched in parallel")
+	flag.Float64Var(&cfg.rps, "rps", 0, "max api requests per second across all accounts, including retries (0 means unlimited)")
+	flag.IntVar(&cfg.maxPages, "max-pages", defaultMaxPages, "fail if the period needs activity pages older than this many weeks (0 means no limit)")
+	flag.IntVar(&cfg.retentionWeeks, "retention-weeks", defaultRetentionWeeks, "warn if the period starts more than this many weeks ago, as activity may be unavailable (0 disables)")
+	flag.IntVar(&cfg.retries, "retries", todoist.DefaultMaxRetries, "max retries on rate limit, 502/503 and network errors")
+	flag.DurationVar(&cfg.projectCacheTTL, "project-cache-ttl", defaultProjectCacheTTL, "how long the project list is cached on disk")
+	flag.BoolVar(&cfg.noCache, "no-cache", false, "do not use the on-disk caches (projects and activity pages)")
+	flag.StringVar(&cfg.color, "color", colorAuto, "colorize output such as the heatmap format (auto, always, never). auto colorizes only when writing to a terminal")
+	noColor := flag.Bool("no-color", false, "same as --color never")
+	flag.BoolVar(&cfg.noUnicode, "no-unicode", false, "print the --summary trend as numbers instead of unicode block characters")
+	flag.BoolVar(&cfg.verbose, "verbose", false, "log api requests and fetched pages to stderr")
+	flag.BoolVar(&cfg.quiet, "quiet", false, "print only the report and fatal errors. hides the fetch progress, warnings and log timestamps")
+	serveAddr := flag.String("serve", "", "start an http server on this address (e.g. :8080) serving /report instead of printing a report")
+	flag.BoolVar(&cfg.failIfEmpty, "fail-if-empty", false, "exit with code 4 when no events are found in the period")
+	flag.BoolVar(&cfg.countOnly, "count-only", false, "print only the number of events after filtering")
+	flag.StringVar(&cfg.slackWebhook, "slack-webhook", "", "post the report to this slack incoming webhook url (default: $TODOIST_SLACK_WEBHOOK)")
+	flag.StringVar(&cfg.exec, "exec", "", "shell command run after the report is written, with the report on stdin and $TODOIST_REPORT_TOTAL, $TODOIST_REPORT_PERIOD set")
+	flag.BoolVar(&cfg.relative, "relative", false, "show event dates relative to now, such as \"2 hours ago\" or \"yesterday\" (json keeps iso timestamps)")
+	flag.BoolVar(&cfg.withNotes, "with-notes", false, "show the comments of each task under its line, for tasks that are still active such as recurring tasks (text, markdown, json, jsonl only)")
+	flag.BoolVar(&cfg.skipEmpty, "skip-empty-content", false, "skip events whose task content is empty (e.g. deleted tasks) instead of showing \"(deleted task <id>)\"")
+	errorsFormat := flag.String("errors", errorsText, "error output format: text or json ({\"error\": \"...\", \"code\": N} on stderr). "+exitCodesHelp)
+	flag.StringVar(&cfg.logFormat, "log-format", logFormatText, "log format: text or json (json lines with level, msg, time)")
+	flag.BoolVar(&cfg.raw, "raw", false, "print the unmodified activity log responses as a json array for debugging")
+	flag.BoolVar(&cfg.dryRun, "dry-run", false, "resolve projects and print the fetch plan without requesting activity logs (with --slack-webhook, fetch events and print the slack payload without posting)")
+	showVersion := flag.Bool("version", false, "print version information and exit")
+	flag.Parse()
 
-	// 今日から数えて、指定した年/月の月初（1日）が何周前か計算する
-	since := time.Since(targetDate)
-	endPage := int(since.Seconds() / 60 / 60 / 24 / 7)
-	startPage := endPage - 5 // 1ヶ月最大でも5週間なので開始を5週間前にしたらOK
-	if startPage < 0 {
-		startPage = 0 // 0スタートなので0以下になったら最初から取得する
+	if *showVersion {
+		fmt.Println(versionString())
+		return
 	}
-	//fmt.Println(startPage, endPage)
 
-	for i := startPage; i <= endPage; i++ {
-		// TODO: 1weekで100タスク以上をこなすケースが対応できていない（count > 100だったら offsetを調整して再起的に呼び出す必要がある）
-		response, err := getActivityLog(ctx, *apiToken, projectID, i, 0, 100)
-		if err != nil {
-			log.Fatalln(err)
-		}
-		//fmt.Printf("total=%d\n", response.Count)
-
-		for _, event := range response.Events {
-			if targetDate.Month() != event.EventDate.Month() {
-				continue
-			}
-
-			fmt.Printf("%s %s\n",
-				event.EventDate.Format("2006/01/02 15:04:02"),
-				event.ExtraData.Content,
-			)
-		}
+	if err := validateLogFormat(cfg.logFormat); err != nil {
+		log.Fatalln(err)
 	}
-}
-
-type GetProjectsResponse struct {
-	Projects []struct {
-		IsArchived   bool        `json:"is_archived"`
-		Color        string      `json:"color"`
-		Shared       bool        `json:"shared"`
-		InboxProject bool        `json:"inbox_project"`
-		ID           string      `json:"id"`
-		Collapsed    bool        `json:"collapsed"`
-		ChildOrder   int         `json:"child_order"`
-		Name         string      `json:"name"`
-		IsDeleted    bool        `json:"is_deleted"`
-		ParentID     interface{} `json:"parent_id"`
-		ViewStyle    string      `json:"view_style"`
-	} `json:"projects"`
-	FullSync      bool `json:"full_sync"`
-	TempIDMapping struct {
-	} `json:"temp_id_mapping"`
-	SyncToken string `json:"sync_token"`
-}
-
-func searchProjectByName(ctx context.Context, apiToken string, projectName string) (string, error) {
-	response, err := getProjects(ctx, apiToken)
-	if err != nil {
-		return "", fmt.Errorf("get project error: %w", err)
+	if err := validateErrorsFormat(*errorsFormat); err != nil {
+		log.Fatalln(err)
 	}
-
-	for _, project := range response.Projects {
-		if projectName == project.Name {
-			return project.ID, nil
-		}
+	setupLogger(cfg.logFormat, cfg.quiet)
+	reporter := errorReporter{format: *errorsFormat, logger: newErrorLogger(cfg.logFormat, cfg.quiet)}
+	fail := func(err error) {
+		reporter.exit(err, exitCode(err))
 	}
 
-	return "", errors.New("project not exists")
-}
-
-const syncGetURL = "https://api.todoist.com/sync/v9/sync"
-
-func getProjects(ctx context.Context, apiToken string) (GetProjectsResponse, error) {
-	getURL, err := url.Parse(syncGetURL)
+	setFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+	fc, err := loadFileConfig(*configPath)
 	if err != nil {
-		return GetProjectsResponse{}, fmt.Errorf("url parse error: %w", err)
+		fail(err)
 	}
+	cfg.accounts = accounts
+	cfg.applyFileConfig(fc, setFlags)
 
-	payload := map[string]interface{}{
-		"sync_token":     "*",
-		"resource_types": []string{"projects"},
+	if *noColor {
+		cfg.color = colorNever
 	}
-
-	var buf bytes.Buffer
-	if err := json.NewEncoder(&buf).Encode(&payload); err != nil {
-		return GetProjectsResponse{}, fmt.Errorf("payload marshal error: %w", err)
+	// シークレットや Webhook の URL はシェルの履歴に残らないように環境変数からも読む
+	if cfg.oauth.ClientSecret == "" {
+		cfg.oauth.ClientSecret = os.Getenv("TODOIST_OAUTH_CLIENT_SECRET")
 	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, getURL.String(), &buf)
-	if err != nil {
-		return GetProjectsResponse{}, fmt.Errorf("new request error: %w", err)
+	if cfg.oauth.RefreshToken == "" {
+		cfg.oauth.RefreshToken = os.Getenv("TODOIST_OAUTH_REFRESH_TOKEN")
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiToken))
-	req.Header.Set("Content-Type", "application/json")
-
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return GetProjectsResponse{}, fmt.Errorf("http request do error: %w", err)
+	if cfg.slackWebhook == "" {
+		cfg.slackWebhook = os.Getenv("TODOIST_SLACK_WEBHOOK")
 	}
-
-	data, err := io.ReadAll(res.Body)
+	cfg.sharing, err = newSharingFilter(*sharedOnly, *personalOnly)
 	if err != nil {
-		return GetProjectsResponse{}, fmt.Errorf("http get response read error: %w", err)
-	}
-
-	var response GetProjectsResponse
-	if err := json.Unmarshal(data, &response); err != nil {
-		return GetProjectsResponse{}, fmt.Errorf("http get response json unmarshall error: %w", err)
+		fail(err)
 	}
-
-	return response, nil
-}
-
-const activityLogGetURL = "https://api.todoist.com/sync/v9/activity/get"
-
-type GetActivityLogResponse struct {
-	Events []struct {
-		ID              uint64    `json:"id"`
-		ObjectType      string    `json:"object_type"`
-		ObjectID        string    `json:"object_id"`
-		EventType       string    `json:"event_type"`
-		EventDate       time.Time `json:"event_date"`
-		ParentProjectID string    `json:"parent_project_id"`
-		ParentItemID    *string   `json:"parent_item_id"`
-		InitiatorID     *string   `json:"initiator_id"`
-		ExtraData       struct {
-			LastDueDate *time.Time `json:"last_due_date"`
-			DueDate     time.Time  `json:"due_date"`
-			Content     string     `json:"content"`
-			Client      string     `json:"client"`
-		} `json:"extra_data,omitempty"`
-	} `json:"events"`
-	Count int `json:"count"`
-}
-
-func getActivityLog(ctx context.Context, apiToken string, projectID string, page int, offset int, limit int) (GetActivityLogResponse, error) {
-	getURL, err := url.Parse(activityLogGetURL)
+	cfg.exclude, err = newProjectExcluder(splitList(*excludePatterns), *noInbox)
 	if err != nil {
-		return GetActivityLogResponse{}, fmt.Errorf("url parse error: %w", err)
+		fail(err)
 	}
 
-	params := url.Values{}
-	params.Add("event_type", "completed")
-	params.Add("parent_project_id", projectID)
-	params.Add("page", strconv.Itoa(page))
-	params.Add("offset", strconv.Itoa(offset))
-	params.Add("limit", strconv.Itoa(limit))
-	getURL.RawQuery = params.Encode()
+	// Ctrl-C で取得中のリクエストを中断して終了する
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, getURL.String(), nil)
-	if err != nil {
-		return GetActivityLogResponse{}, fmt.Errorf("new request error: %w", err)
-	}
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", apiToken))
-
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return GetActivityLogResponse{}, fmt.Errorf("http request do error: %w", err)
+	if *serveAddr != "" {
+		if err := serve(ctx, *serveAddr, cfg); err != nil {
+			fail(err)
+		}
+		return
 	}
 
-	data, err := io.ReadAll(res.Body)
-	if err != nil {
-		return GetActivityLogResponse{}, fmt.Errorf("http get response read error: %w", err)
+	runReport := run
+	if cfg.projectName == projectsFromStdin {
+		runReport = func(ctx context.Context, cfg config) error {
+			return runProjectsFromStdin(ctx, cfg, os.Stdin)
+		}
 	}
-
-	var response GetActivityLogResponse
-	if err := json.Unmarshal(data, &response); err != nil {
-		return GetActivityLogResponse{}, fmt.Errorf("http get response json unmarshall error: %w", err)
+	if err := runReport(ctx, cfg); err != nil {
+		// stop() するとコンテキストがキャンセル扱いになるので、先にシグナルで中断されたかを確認する
+		interrupted := ctx.Err() != nil
+		stop()
+		if interrupted {
+			reporter.exit(errors.New("interrupted: no report was written"), exitCodeInterrupted)
+		}
+		fail(err)
 	}
-
-	return response, nil
 }
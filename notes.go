@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"todoistreport/todoist"
+)
+
+// タスクの ID ごとのコメント。削除したコメントは含めず、投稿した順に並べる
+// Sync API の notes は未完了のタスク（items）のコメントしか返さないため、完了して残っていないタスクの
+// コメントは取得できない。完了したイベントでコメントが付くのは、繰り返しタスクなど未完了のまま残っているものだけになる
+func fetchItemNotes(ctx context.Context, client *todoist.Client) (map[string][]string, error) {
+	response, err := client.Notes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get notes error: %w", err)
+	}
+
+	notes := response.Notes
+	sort.SliceStable(notes, func(i, j int) bool {
+		return notes[i].PostedAt.Before(notes[j].PostedAt)
+	})
+	itemNotes := make(map[string][]string)
+	for _, note := range notes {
+		if note.IsDeleted || strings.TrimSpace(note.Content) == "" {
+			continue
+		}
+		itemNotes[note.ItemID] = append(itemNotes[note.ItemID], note.Content)
+	}
+	return itemNotes, nil
+}
+
+// --with-notes で、イベントのタスクのコメントを Notes に入れる。コメントの無いタスクはそのまま
+func attachNotes(events []Event, itemNotes map[string][]string) []Event {
+	for i := range events {
+		if events[i].TaskID != "" {
+			events[i].Notes = itemNotes[events[i].TaskID]
+		}
+	}
+	return events
+}
+
+// テキスト形式でタスクの行の下に出力するコメントの行。複数行のコメントは行ごとに同じ字下げにする
+func noteLines(event Event, indent string) []string {
+	var lines []string
+	for _, note := range event.Notes {
+		for i, line := range strings.Split(strings.TrimSpace(note), "\n") {
+			prefix := "  "
+			if i == 0 {
+				prefix = "- "
+			}
+			lines = append(lines, indent+"    "+prefix+strings.TrimRight(line, "\r"))
+		}
+	}
+	return lines
+}
+
+// Markdown の表ではタスクの行の下に行を足せないので、タスクの内容のセルに改行（<br>）でつなげる
+func markdownNotes(event Event) string {
+	var b strings.Builder
+	for _, note := range event.Notes {
+		b.WriteString("<br>- " + escapeMarkdownCell(strings.TrimSpace(note)))
+	}
+	return b.String()
+}
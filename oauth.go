@@ -0,0 +1,22 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// --oauth-* はリフレッシュトークンとクライアントの情報が全て揃っている場合だけ使える
+// 更新したアクセストークンは1つのアカウントの Client の中で持つので、複数のアカウントでは使えない
+func validateOAuth(cfg config) error {
+	o := cfg.oauth
+	if o.ClientID == "" && o.ClientSecret == "" && o.RefreshToken == "" {
+		return nil
+	}
+	if o.ClientID == "" || o.ClientSecret == "" || o.RefreshToken == "" {
+		return fmt.Errorf("--oauth-client-id, --oauth-client-secret and --oauth-refresh-token must be specified together")
+	}
+	if len(cfg.accounts) > 1 {
+		return errors.New("--oauth-refresh-token is not supported with multiple --token")
+	}
+	return nil
+}
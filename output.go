@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// outputPathData は --output のファイル名テンプレートに渡す値
+// 例: report-{{.Year}}-{{.Month}}.md
+type outputPathData struct {
+	Year  string
+	Month string
+	Since string
+	Until string
+}
+
+func outputPath(pathTemplate string, p period) (string, error) {
+	tmpl, err := template.New("output").Option("missingkey=error").Parse(pathTemplate)
+	if err != nil {
+		return "", fmt.Errorf("output path template parse error: %w", err)
+	}
+
+	data := outputPathData{
+		Year:  p.since.Format("2006"),
+		Month: p.since.Format("01"),
+		Since: p.since.Format("20060102"),
+		Until: p.until.Format("20060102"),
+	}
+
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("output path template execute error: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// 親ディレクトリが無ければ作成してから書き込む
+// tee が nil でなければ同じ内容を tee（--tee なら標準出力）にも書き込む
+// tee に先に書き込むので、ファイルの作成や書き込みに失敗しても tee には出力される
+func writeOutputFile(path string, data []byte, tee io.Writer) error {
+	f, err := createOutputFile(path)
+	if err != nil {
+		if tee != nil {
+			if _, teeErr := tee.Write(data); teeErr != nil {
+				return fmt.Errorf("write error: %w", teeErr)
+			}
+		}
+		return err
+	}
+
+	w := io.Writer(f)
+	if tee != nil {
+		w = io.MultiWriter(tee, f)
+	}
+	if _, err := w.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("output file write error: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("output file close error: %w", err)
+	}
+	return nil
+}
+
+func createOutputFile(path string) (*os.File, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("output dir create error: %w", err)
+		}
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("output file create error: %w", err)
+	}
+	return f, nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+const (
+	yearLayout  = "2006"
+	monthLayout = "2006/01"
+	dateLayout  = "2006/01/02"
+)
+
+// period はレポート対象の期間 [since, until)
+type period struct {
+	since time.Time
+	until time.Time
+}
+
+func (p period) contains(t time.Time) bool {
+	return !t.Before(p.since) && t.Before(p.until)
+}
+
+// 月の初めから1か月間の期間かどうか
+func (p period) isMonth() bool {
+	return p.since.Day() == 1 && p.since.Hour() == 0 && p.until.Equal(p.since.AddDate(0, 1, 0))
+}
+
+// 年の初めから1年間の期間かどうか
+func (p period) isYear() bool {
+	return p.since.YearDay() == 1 && p.since.Hour() == 0 && p.until.Equal(p.since.AddDate(1, 0, 0))
+}
+
+// 年単位の期間なら YYYY、月単位の期間なら YYYY/MM、それ以外は YYYY/MM/DD - YYYY/MM/DD で表す。until は含まないので前日を表示する
+func (p period) label() string {
+	if p.isYear() {
+		return p.since.Format(yearLayout)
+	}
+	if p.isMonth() {
+		return p.since.Format(monthLayout)
+	}
+	return fmt.Sprintf("%s - %s", p.since.Format(dateLayout), p.until.Add(-time.Nanosecond).Format(dateLayout))
+}
+
+func monthPeriod(targetDate time.Time) period {
+	return period{
+		since: targetDate,
+		until: targetDate.AddDate(0, 1, 0),
+	}
+}
+
+func yearPeriod(targetDate time.Time) period {
+	return period{
+		since: targetDate,
+		until: targetDate.AddDate(1, 0, 0),
+	}
+}
+
+// --target を期間に変換する。YYYY の場合はその年全体、YYYY/MM の場合はその月全体にする
+func parseTarget(value string, loc *time.Location) (period, error) {
+	if len(value) == len(yearLayout) {
+		targetDate, err := time.ParseInLocation(yearLayout, value, loc)
+		if err != nil {
+			return period{}, fmt.Errorf("target parse error: %w", err)
+		}
+		return yearPeriod(targetDate), nil
+	}
+	targetDate, err := time.ParseInLocation(monthLayout, value, loc)
+	if err != nil {
+		return period{}, fmt.Errorf("target parse error: %w", err)
+	}
+	return monthPeriod(targetDate), nil
+}
+
+// 期間を先頭から months ヶ月ずつに分ける。最後の期間は p.until までになる
+func splitPeriod(p period, months int) []period {
+	var result []period
+	for since := p.since; since.Before(p.until); {
+		until := since.AddDate(0, months, 0)
+		if until.After(p.until) {
+			until = p.until
+		}
+		result = append(result, period{since: since, until: until})
+		since = until
+	}
+	return result
+}
+
+// --since, --until を期間に変換する。--until を省略した場合は now までを対象にする
+func parseDateRange(sinceValue, untilValue string, now time.Time, loc *time.Location) (period, error) {
+	if sinceValue == "" {
+		return period{}, errors.New("--since is required when --until is specified")
+	}
+
+	since, err := time.ParseInLocation(dateLayout, sinceValue, loc)
+	if err != nil {
+		return period{}, fmt.Errorf("since parse error: %w", err)
+	}
+
+	until := now
+	if untilValue != "" {
+		until, err = time.ParseInLocation(dateLayout, untilValue, loc)
+		if err != nil {
+			return period{}, fmt.Errorf("until parse error: %w", err)
+		}
+	}
+
+	if !since.Before(until) {
+		return period{}, fmt.Errorf("since %s must be before until %s", sinceValue, until.Format(dateLayout))
+	}
+
+	return period{since: since, until: until}, nil
+}
+
+// 空の場合はシステムのローカルタイムゾーンを使う
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("timezone load error: %w", err)
+	}
+	return loc, nil
+}
+
+// todoistのアクティビティログのページは、今週（月曜始まり）を0ページ目として何週前かを表す
+// t が now から数えて何ページ目に含まれるかを返す。未来の日時は0ページ目とする
+func pageOf(now, t time.Time, loc *time.Location) int {
+	weeks := int(math.Round(startOfWeek(now, loc).Sub(startOfWeek(t, loc)).Hours() / 24 / 7))
+	if weeks < 0 {
+		return 0
+	}
+	return weeks
+}
+
+// t を含む週の月曜日の0時
+func startOfWeek(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, loc)
+}
+
+// 安全のため、デフォルトでは約2年前（104週前）のページまでしか取得しない
+const defaultMaxPages = 104
+
+// endPage が maxPages を超える（古すぎる期間を指定した）場合はエラーにする。maxPages が0の場合は制限しない
+// Todoist はアクティビティログを無期限には保持していないので、そこまで古いページを取得しても空になる可能性が高い
+func checkMaxPages(p period, endPage, maxPages int) error {
+	if maxPages <= 0 || endPage <= maxPages {
+		return nil
+	}
+	return fmt.Errorf("period %s needs activity log pages up to %d (weeks ago), which exceeds --max-pages %d. Todoist may not retain activity that old", p.label(), endPage, maxPages)
+}
+
+// 無料プランのアカウントなどでアクティビティログが残っていない可能性がある目安（週）
+const defaultRetentionWeeks = 12
+
+// endPage が retentionWeeks を超える場合は、データが残っていない可能性があることを警告する。取得はそのまま行う
+func warnRetention(p period, endPage, retentionWeeks int) {
+	if retentionWeeks <= 0 || endPage <= retentionWeeks {
+		return
+	}
+	log.Printf("warning: period %s starts %d weeks ago. activity older than %d weeks may be unavailable due to Todoist's retention limits (see --retention-weeks)\n", p.label(), endPage, retentionWeeks)
+}
+
+// 期間 p 全体を取得するのに必要な最小のページ範囲を返す。期間の両端の週の途中から・途中までの分も含める
+// 年単位の期間は12か月分の全ての週のページになる
+func pageRangeForPeriod(now time.Time, p period, loc *time.Location) (startPage, endPage int) {
+	// until は期間に含まないので、期間の最後の瞬間が含まれるページまで取得する
+	startPage = pageOf(now, p.until.Add(-time.Nanosecond), loc)
+	endPage = pageOf(now, p.since, loc)
+	return startPage, endPage
+}
+
+// targetMonth の月全体を取得するのに必要な最小のページ範囲を返す
+// 今月を指定した場合は未来の日時が0ページ目になるので、月初の週から今週までのページだけになる
+func computePageRange(now, targetMonth time.Time, loc *time.Location) (startPage, endPage int) {
+	targetMonth = targetMonth.In(loc)
+	firstDay := time.Date(targetMonth.Year(), targetMonth.Month(), 1, 0, 0, 0, 0, loc)
+	return pageRangeForPeriod(now, monthPeriod(firstDay), loc)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputePageRange(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	// 2024/03/20 は水曜日なので、今週（0ページ目）は 2024/03/18 の月曜日から
+	now := time.Date(2024, 3, 20, 12, 0, 0, 0, loc)
+
+	tests := []struct {
+		name          string
+		target        time.Time
+		wantStartPage int
+		wantEndPage   int
+	}{
+		{name: "current month", target: time.Date(2024, 3, 1, 0, 0, 0, 0, loc), wantStartPage: 0, wantEndPage: 3},
+		{name: "leap year february", target: time.Date(2024, 2, 1, 0, 0, 0, 0, loc), wantStartPage: 3, wantEndPage: 7},
+		{name: "month starting on monday", target: time.Date(2024, 1, 1, 0, 0, 0, 0, loc), wantStartPage: 7, wantEndPage: 11},
+		{name: "previous year", target: time.Date(2023, 12, 1, 0, 0, 0, 0, loc), wantStartPage: 12, wantEndPage: 16},
+		{name: "future month", target: time.Date(2024, 4, 1, 0, 0, 0, 0, loc), wantStartPage: 0, wantEndPage: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			startPage, endPage := computePageRange(now, tt.target, loc)
+			if startPage != tt.wantStartPage || endPage != tt.wantEndPage {
+				t.Errorf("computePageRange(%s) = %d..%d, want %d..%d", tt.target.Format(monthLayout), startPage, endPage, tt.wantStartPage, tt.wantEndPage)
+			}
+		})
+	}
+}
+
+func TestComputePageRangeLeapDay(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	now := time.Date(2024, 3, 20, 12, 0, 0, 0, loc)
+	// 閏日の最後の瞬間も2月のページの範囲に含まれる
+	leapDay := time.Date(2024, 2, 29, 23, 59, 59, 0, loc)
+	startPage, endPage := computePageRange(now, time.Date(2024, 2, 1, 0, 0, 0, 0, loc), loc)
+	if page := pageOf(now, leapDay, loc); page < startPage || page > endPage {
+		t.Errorf("pageOf(2024/02/29) = %d, want within %d..%d", page, startPage, endPage)
+	}
+}
+
+func TestPageRangeForPeriodPinnedNow(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	pinned := time.Date(2024, 3, 20, 12, 0, 0, 0, loc)
+	cfg := config{now: func() time.Time { return pinned }}
+	now := cfg.currentTime()
+
+	currentWeek, err := parseDateRange("2024/03/18", "", now, loc)
+	if err != nil {
+		t.Fatalf("parseDateRange() error = %v", err)
+	}
+	sixMonthsAgo, err := parseTarget("2023/09", loc)
+	if err != nil {
+		t.Fatalf("parseTarget() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		p             period
+		wantStartPage int
+		wantEndPage   int
+	}{
+		{name: "current week", p: currentWeek, wantStartPage: 0, wantEndPage: 0},
+		{name: "six months back", p: sixMonthsAgo, wantStartPage: 25, wantEndPage: 29},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			startPage, endPage := pageRangeForPeriod(now, tt.p, loc)
+			if startPage != tt.wantStartPage || endPage != tt.wantEndPage {
+				t.Errorf("pageRangeForPeriod(%s) = %d..%d, want %d..%d", tt.p.label(), startPage, endPage, tt.wantStartPage, tt.wantEndPage)
+			}
+		})
+	}
+}
+
+func TestSplitPeriod(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	year, err := parseTarget("2023", loc)
+	if err != nil {
+		t.Fatalf("parseTarget() error = %v", err)
+	}
+	month, err := parseTarget("2023/05", loc)
+	if err != nil {
+		t.Fatalf("parseTarget() error = %v", err)
+	}
+	span, err := parseDateRange("2023/01/15", "2023/05/10", time.Time{}, loc)
+	if err != nil {
+		t.Fatalf("parseDateRange() error = %v", err)
+	}
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, loc) }
+
+	tests := []struct {
+		name string
+		p    period
+		want []period
+	}{
+		{name: "year", p: year, want: []period{
+			{since: date(2023, 1, 1), until: date(2023, 4, 1)},
+			{since: date(2023, 4, 1), until: date(2023, 7, 1)},
+			{since: date(2023, 7, 1), until: date(2023, 10, 1)},
+			{since: date(2023, 10, 1), until: date(2024, 1, 1)},
+		}},
+		{name: "month", p: month, want: []period{month}},
+		{name: "last chunk ends at until", p: span, want: []period{
+			{since: date(2023, 1, 15), until: date(2023, 4, 15)},
+			{since: date(2023, 4, 15), until: date(2023, 5, 10)},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitPeriod(tt.p, completedItemsMaxMonths)
+			if len(got) != len(tt.want) {
+				t.Fatalf("splitPeriod() = %d periods, want %d", len(got), len(tt.want))
+			}
+			for i := range tt.want {
+				if !got[i].since.Equal(tt.want[i].since) || !got[i].until.Equal(tt.want[i].until) {
+					t.Errorf("splitPeriod()[%d] = %s, want %s", i, got[i].label(), tt.want[i].label())
+				}
+			}
+		})
+	}
+}
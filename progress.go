@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// progress はページの取得状況を標準エラー出力の1行に上書きしながら表示する
+// nil の場合は何も表示しない
+type progress struct {
+	mu     sync.Mutex
+	w      io.Writer
+	total  int
+	done   int
+	events int
+}
+
+func newProgress(w io.Writer, total int) *progress {
+	return &progress{w: w, total: total}
+}
+
+// 1ページ取得するたびに呼ぶ。複数の goroutine から呼んでよい
+func (p *progress) add(events int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	p.events += events
+	fmt.Fprintf(p.w, "\rfetched page %d of %d, %d events so far", p.done, p.total, p.events)
+}
+
+// 表示した行を消して、後に続くログやレポートと混ざらないようにする
+func (p *progress) finish() {
+	if p == nil || p.done == 0 {
+		return
+	}
+	fmt.Fprint(p.w, "\r\x1b[K")
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Prometheus のテキスト形式で、プロジェクト・イベント種別ごとの期間内の件数を出力する
+// メトリクス名はイベント種別から作る（completed なら todoist_completed_total）
+// 件数は --limit で切り詰める前の全てのイベントで数える
+func renderPrometheus(w io.Writer, r report, daily bool) error {
+	eventTypes := append([]string(nil), r.eventTypes...)
+	totals := make(map[string]map[string]int)
+	for _, eventType := range eventTypes {
+		totals[eventType] = make(map[string]int)
+	}
+	for _, event := range r.allEvents {
+		if _, ok := totals[event.EventType]; !ok {
+			totals[event.EventType] = make(map[string]int)
+			eventTypes = append(eventTypes, event.EventType)
+		}
+		totals[event.EventType][event.Project]++
+	}
+	// 指定したプロジェクトは0件でも出力する
+	for _, counts := range totals {
+		for _, project := range r.projects {
+			counts[project] += 0
+		}
+	}
+
+	periodLabels := prometheusPeriodLabels(r.period)
+
+	var buf strings.Builder
+	for _, eventType := range eventTypes {
+		name := prometheusMetricName("todoist_" + eventType + "_total")
+		fmt.Fprintf(&buf, "# HELP %s Number of %s events in the report period.\n", name, eventType)
+		fmt.Fprintf(&buf, "# TYPE %s gauge\n", name)
+		for _, project := range sortedKeys(totals[eventType]) {
+			fmt.Fprintf(&buf, "%s{project=\"%s\",%s} %d\n", name, escapePrometheusLabel(project), periodLabels, totals[eventType][project])
+		}
+	}
+
+	if daily {
+		for _, eventType := range eventTypes {
+			name := prometheusMetricName("todoist_" + eventType + "_daily")
+			fmt.Fprintf(&buf, "# HELP %s Number of %s events per day.\n", name, eventType)
+			fmt.Fprintf(&buf, "# TYPE %s gauge\n", name)
+			for _, project := range sortedKeys(totals[eventType]) {
+				var events []Event
+				for _, event := range r.allEvents {
+					if event.EventType == eventType && event.Project == project {
+						events = append(events, event)
+					}
+				}
+				for _, c := range countByDay(events, r.period) {
+					fmt.Fprintf(&buf, "%s{project=\"%s\",date=\"%s\"} %d\n", name, escapePrometheusLabel(project), c.Date.Format("2006-01-02"), c.Count)
+				}
+			}
+		}
+	}
+
+	if _, err := io.WriteString(w, buf.String()); err != nil {
+		return fmt.Errorf("write error: %w", err)
+	}
+	return nil
+}
+
+// 月単位の期間は month="2006-01"、それ以外は since, until（until は含まない）のラベルにする
+func prometheusPeriodLabels(p period) string {
+	if p.isMonth() {
+		return fmt.Sprintf("month=\"%s\"", p.since.Format("2006-01"))
+	}
+	return fmt.Sprintf("since=\"%s\",until=\"%s\"", p.since.Format("2006-01-02"), p.until.Format("2006-01-02"))
+}
+
+// メトリクス名に使えない文字（[a-zA-Z0-9_:] 以外）を _ に置き換える。先頭が数字の場合は _ を付ける
+func prometheusMetricName(name string) string {
+	var buf strings.Builder
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == ':':
+			buf.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				buf.WriteRune('_')
+			}
+			buf.WriteRune(r)
+		default:
+			buf.WriteRune('_')
+		}
+	}
+	return buf.String()
+}
+
+var prometheusLabelEscaper = strings.NewReplacer(
+	"\\", "\\\\",
+	"\"", "\\\"",
+	"\n", "\\n",
+)
+
+func escapePrometheusLabel(s string) string {
+	return prometheusLabelEscaper.Replace(s)
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
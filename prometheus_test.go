@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// --limit で一覧を切り詰めても、メトリクスは Total と同じく全てのイベントで数える
+func TestRenderPrometheusIgnoresLimit(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	p := monthPeriod(time.Date(2024, 3, 1, 0, 0, 0, 0, loc))
+	events := []Event{
+		{ID: "1", Project: "Home", EventType: "completed", Date: time.Date(2024, 3, 1, 9, 0, 0, 0, loc)},
+		{ID: "2", Project: "Work", EventType: "completed", Date: time.Date(2024, 3, 2, 9, 0, 0, 0, loc)},
+		{ID: "3", Project: "Work", EventType: "completed", Date: time.Date(2024, 3, 2, 10, 0, 0, 0, loc)},
+	}
+	r := newReport("Home, Work", p, events).limit(1, orderAsc)
+	r.eventTypes = []string{"completed"}
+
+	var buf bytes.Buffer
+	if err := renderPrometheus(&buf, r, true); err != nil {
+		t.Fatalf("renderPrometheus() error = %v", err)
+	}
+	for _, want := range []string{
+		`todoist_completed_total{project="Home",month="2024-03"} 1`,
+		`todoist_completed_total{project="Work",month="2024-03"} 2`,
+		`todoist_completed_daily{project="Work",date="2024-03-02"} 2`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("renderPrometheus() output does not contain %q:\n%s", want, buf.String())
+		}
+	}
+}
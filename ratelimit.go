@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// rateLimiter は1秒あたり rps 回までリクエストを送るトークンバケット
+// バケットの容量は1なので、同時に取得するページが多くてもまとめて送らず、間隔を空けて送る
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	// 次のリクエストを送ってよい時刻
+	next time.Time
+}
+
+func newRateLimiter(rps float64) *rateLimiter {
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / rps)}
+}
+
+// 順番を予約して、送ってよい時刻まで待つ。ctx がキャンセルされた場合は待たずにエラーを返す
+// todoist.RateLimiter として todoist.WithRateLimiter に渡す
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	at := l.next
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func validateRPS(rps float64) error {
+	if rps < 0 {
+		return fmt.Errorf("--rps must be 0 or greater, got %g", rps)
+	}
+	return nil
+}
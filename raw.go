@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"todoistreport/todoist"
+)
+
+// --raw で、アクティビティログのレスポンスの JSON を加工せずに取得する
+// 1ページに Limit 件以上ある場合は、ActivityLogAll と同じように count に達するまで offset をずらして取得する
+func fetchRawPages(ctx context.Context, client *todoist.Client, requests []pageRequest, eventTypes []string) ([]json.RawMessage, error) {
+	var bodies []json.RawMessage
+	for _, request := range requests {
+		opts := todoist.ActivityLogOptions{
+			ProjectID:  request.Project.ID,
+			Page:       request.Page,
+			EventTypes: eventTypes,
+		}
+		for {
+			raw, err := client.ActivityLogRaw(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			bodies = append(bodies, raw)
+
+			// 次の offset を決めるために件数だけ読む
+			var page struct {
+				Events []json.RawMessage `json:"events"`
+				Count  int               `json:"count"`
+			}
+			if err := json.Unmarshal(raw, &page); err != nil {
+				return nil, fmt.Errorf("json unmarshall error: %w", err)
+			}
+			opts.Offset += len(page.Events)
+			if len(page.Events) == 0 || opts.Offset >= page.Count {
+				break
+			}
+		}
+	}
+	return bodies, nil
+}
+
+// レスポンスを1つの JSON の配列にまとめる。json.Marshal は RawMessage を整形し直すので、そのままの内容で連結する
+func rawArray(bodies []json.RawMessage) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("[")
+	for i, body := range bodies {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n")
+		buf.Write(body)
+	}
+	buf.WriteString("\n]\n")
+	return buf.Bytes()
+}
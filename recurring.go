@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// 同じプロジェクトで同じ内容のイベント（繰り返しタスクの毎回の完了など）を1件にまとめる
+// まとめたイベントは最初に出てくる位置に置き、Recurrence に完了回数と最初・最後の日付を入れる。1回だけのイベントはそのままにする
+func collapseRecurring(events []Event) []Event {
+	type recurrence struct {
+		count       int
+		first, last time.Time
+	}
+	key := func(e Event) string { return e.Project + "\x00" + e.Content }
+
+	recurrences := make(map[string]*recurrence)
+	for _, event := range events {
+		r, ok := recurrences[key(event)]
+		if !ok {
+			r = &recurrence{first: event.Date, last: event.Date}
+			recurrences[key(event)] = r
+		}
+		r.count++
+		if event.Date.Before(r.first) {
+			r.first = event.Date
+		}
+		if event.Date.After(r.last) {
+			r.last = event.Date
+		}
+	}
+
+	var result []Event
+	seen := make(map[string]bool)
+	for _, event := range events {
+		k := key(event)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		if r := recurrences[k]; r.count > 1 {
+			event.Recurrence = fmt.Sprintf("(%d times, %s - %s)", r.count, r.first.Format(dateLayout), r.last.Format(dateLayout))
+		}
+		result = append(result, event)
+	}
+	return result
+}
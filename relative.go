@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// --relative で日時を "3 days ago" のように now からの相対的な表現にする
+// 日付の区切り（yesterday など）は t のタイムゾーン（--tz）で判定する
+func relativeDate(t, now time.Time) string {
+	now = now.In(t.Location())
+	d := now.Sub(t)
+	if d < time.Minute {
+		return "just now"
+	}
+	if d < time.Hour {
+		return ago(int(d/time.Minute), "minute")
+	}
+
+	days := calendarDays(t, now)
+	switch {
+	case days == 0:
+		return ago(int(d/time.Hour), "hour")
+	case days == 1:
+		return "yesterday"
+	case days < 7:
+		return ago(days, "day")
+	case days < 30:
+		return ago(days/7, "week")
+	case days < 365:
+		return ago(days/30, "month")
+	default:
+		return ago(days/365, "year")
+	}
+}
+
+// t の日から now の日まで何日あるか。夏時間で1日が24時間でない日があっても暦の日付で数える
+func calendarDays(t, now time.Time) int {
+	from := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return int(to.Sub(from).Hours() / 24)
+}
+
+func ago(n int, unit string) string {
+	return fmt.Sprintf("%s ago", plural(n, unit))
+}
+
+// opts.relative が設定されている場合は、日時の列を相対的な表現にした列に差し替える
+func withRelativeDates(columns []column, opts renderOptions) []column {
+	if opts.relative.IsZero() {
+		return columns
+	}
+	result := make([]column, len(columns))
+	for i, c := range columns {
+		if c.name == dateColumn.name {
+			c.value = func(e Event) string { return relativeDate(e.Date, opts.relative) }
+		}
+		result[i] = c
+	}
+	return result
+}
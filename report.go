@@ -0,0 +1,490 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const reportDateLayout = "2006/01/02 15:04:05"
+
+type Event struct {
+	Date      time.Time `json:"date"`
+	Content   string    `json:"content"`
+	Project   string    `json:"project,omitempty"`
+	EventType string    `json:"event_type,omitempty"`
+	Client    string    `json:"client,omitempty"`
+	Account   string    `json:"account,omitempty"`
+
+	DueDate     *time.Time `json:"due_date,omitempty"`
+	LastDueDate *time.Time `json:"last_due_date,omitempty"`
+
+	// ID はイベントの ID、TaskID はタスクの ID。JSON では --show-id を指定した場合だけ出力する
+	ID            string `json:"id,omitempty"`
+	ProjectID     string `json:"-"`
+	TaskID        string `json:"task_id,omitempty"`
+	InitiatorID   string `json:"-"`
+	ParentTaskID  string `json:"-"`
+	ParentContent string `json:"parent_content,omitempty"`
+	// --collapse-recurring でまとめた場合の完了回数と期間。タスクの内容の後ろに出力する
+	Recurrence string `json:"-"`
+	// --with-notes を指定した場合のタスクのコメント
+	Notes []string `json:"notes,omitempty"`
+}
+
+type column struct {
+	name  string
+	title string
+	value func(Event) string
+}
+
+var (
+	dateColumn      = column{name: "date", title: "Date", value: func(e Event) string { return e.Date.Format(reportDateLayout) }}
+	contentColumn   = column{name: "content", title: "Task", value: func(e Event) string { return withRecurrence(e) }}
+	projectColumn   = column{name: "project", title: "Project", value: func(e Event) string { return e.Project }}
+	eventTypeColumn = column{name: "event_type", title: "Type", value: func(e Event) string { return e.EventType }}
+	accountColumn   = column{name: "account", title: "Account", value: func(e Event) string { return e.Account }}
+	clientColumn    = column{name: "client", title: "Client", value: func(e Event) string { return clientName(e.Client) }}
+	taskIDColumn    = column{name: "task_id", title: "Task ID", value: func(e Event) string { return e.TaskID }}
+	idColumn        = column{name: "id", title: "Event ID", value: func(e Event) string { return e.ID }}
+)
+
+// --fields で指定できる列
+func fieldColumns() []column {
+	return []column{dateColumn, contentColumn, projectColumn, eventTypeColumn, accountColumn, clientColumn, lastDueDateColumn, dueDateColumn, taskIDColumn, idColumn}
+}
+
+func withRecurrence(e Event) string {
+	if e.Recurrence == "" {
+		return e.Content
+	}
+	return e.Content + " " + e.Recurrence
+}
+
+// JSON に ID を出力しないように、ID とタスクの ID を空にしたコピーを返す
+func withoutIDs(events []Event) []Event {
+	result := make([]Event, len(events))
+	for i, event := range events {
+		event.ID = ""
+		event.TaskID = ""
+		result[i] = event
+	}
+	return result
+}
+
+// --fields のカンマ区切りの列名を、指定した順番の列に変換する
+func parseFields(fields string) ([]column, error) {
+	available := fieldColumns()
+	var columns []column
+	for _, name := range splitList(fields) {
+		found := false
+		for _, c := range available {
+			if c.name == name {
+				columns = append(columns, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			names := make([]string, 0, len(available))
+			for _, c := range available {
+				names = append(names, c.name)
+			}
+			return nil, fmt.Errorf("unknown field %q (available: %s)", name, strings.Join(names, ", "))
+		}
+	}
+	return columns, nil
+}
+
+// renderOptions はイベントの一覧の出力方法のオプション
+type renderOptions struct {
+	// fields を指定した場合は、その列だけをその順番で出力する
+	fields       []column
+	showClient   bool
+	showDueDates bool
+	showID       bool
+	// Prometheus 形式で日ごとの完了数も出力する
+	daily bool
+	// heatmap 形式の表示方法
+	heatmap heatmapOptions
+	// --relative を指定した場合に、相対的な日時の基準にする現在時刻。ゼロ値の場合は日時をそのまま出力する
+	relative time.Time
+}
+
+// date, content の後ろに追加で出力する列を返す
+// 複数のアカウントやプロジェクト、イベント種別が混在している場合は、どのアカウント・プロジェクト・種別のタスクか分かるように出力する
+func extraColumns(events []Event, opts renderOptions) []column {
+	var columns []column
+	for _, c := range []column{accountColumn, projectColumn, eventTypeColumn} {
+		for _, event := range events {
+			if c.value(event) != c.value(events[0]) {
+				columns = append(columns, c)
+				break
+			}
+		}
+	}
+	if opts.showClient {
+		columns = append(columns, clientColumn)
+	}
+	if opts.showDueDates {
+		columns = append(columns, lastDueDateColumn, dueDateColumn)
+	}
+	if opts.showID {
+		columns = append(columns, taskIDColumn, idColumn)
+	}
+	return columns
+}
+
+// csv, markdown で出力する列を返す
+func tableColumns(events []Event, opts renderOptions) []column {
+	if len(opts.fields) > 0 {
+		return withRelativeDates(opts.fields, opts)
+	}
+	return withRelativeDates(append([]column{dateColumn, contentColumn}, extraColumns(events, opts)...), opts)
+}
+
+// text で出力する列を返す。デフォルトでは追加の列は日時とタスクの内容の間に出力する
+func textColumns(events []Event, opts renderOptions) []column {
+	if len(opts.fields) > 0 {
+		return withRelativeDates(opts.fields, opts)
+	}
+	columns := append([]column{dateColumn}, extraColumns(events, opts)...)
+	return withRelativeDates(append(columns, contentColumn), opts)
+}
+
+// text の1行を作る。日時とタスクの内容以外は [] で囲む
+func textLine(event Event, columns []column) string {
+	parts := make([]string, 0, len(columns))
+	for _, c := range columns {
+		if c.name == dateColumn.name || c.name == contentColumn.name {
+			parts = append(parts, c.value(event))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("[%s]", c.value(event)))
+	}
+	return strings.Join(parts, " ")
+}
+
+const (
+	orderAsc  = "asc"
+	orderDesc = "desc"
+)
+
+func validateOrder(order string) error {
+	switch order {
+	case orderAsc, orderDesc:
+		return nil
+	default:
+		return fmt.Errorf("unknown order %q (available: %s, %s)", order, orderAsc, orderDesc)
+	}
+}
+
+// イベントを日時順に並べる。取得したページの順番によらず同じ出力になるように、同じ日時の場合は ID で並べる
+func sortEvents(events []Event, order string) {
+	sort.SliceStable(events, func(i, j int) bool {
+		a, b := events[i], events[j]
+		if order == orderDesc {
+			a, b = b, a
+		}
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		return lessID(a.ID, b.ID)
+	})
+}
+
+// アクティビティログの ID は数値なので、桁数が違う場合は桁数の少ない方を小さいとする
+func lessID(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
+
+const (
+	formatText       = "text"
+	formatJSON       = "json"
+	formatJSONL      = "jsonl"
+	formatCSV        = "csv"
+	formatMarkdown   = "markdown"
+	formatICS        = "ics"
+	formatHTML       = "html"
+	formatChecklist  = "checklist"
+	formatPrometheus = "prometheus"
+	formatHeatmap    = "heatmap"
+	formatDigest     = "digest"
+)
+
+var reportFormats = []string{formatText, formatJSON, formatJSONL, formatCSV, formatMarkdown, formatICS, formatHTML, formatChecklist, formatPrometheus, formatHeatmap, formatDigest}
+
+func validateFormat(format string) error {
+	for _, f := range reportFormats {
+		if format == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown format %q (available: %s)", format, strings.Join(reportFormats, ", "))
+}
+
+func formatIn(format string, formats ...string) bool {
+	for _, f := range formats {
+		if format == f {
+			return true
+		}
+	}
+	return false
+}
+
+// report はレポート全体。ヘッダーに出力するプロジェクト・期間・件数とイベントを持つ
+type report struct {
+	Project string `json:"project"`
+	Period  string `json:"period"`
+	Total   int    `json:"total"`
+	// --parse-estimates を指定した場合の見積もり時間の合計
+	Estimate string  `json:"estimate,omitempty"`
+	Events   []Event `json:"events"`
+
+	period period
+	// --limit で切り詰める前の全てのイベント。html の日ごとの完了数を Total と合わせるために使う
+	allEvents []Event
+	// Prometheus 形式で0件のプロジェクト・イベント種別も出力するために使う。projects が空の場合はアカウント全体
+	projects   []string
+	eventTypes []string
+}
+
+func newReport(project string, p period, events []Event) report {
+	// イベントが0件でも JSON で null ではなく [] を出力する
+	if events == nil {
+		events = []Event{}
+	}
+	return report{
+		Project:   project,
+		Period:    p.label(),
+		Total:     len(events),
+		Events:    events,
+		period:    p,
+		allEvents: events,
+	}
+}
+
+// sortEvents で order の順に並べたイベントを、新しいものから n 件までに絞り込む
+// Total は絞り込む前の件数のままにする
+func (r report) limit(n int, order string) report {
+	if n <= 0 || len(r.Events) <= n {
+		return r
+	}
+	if order == orderDesc {
+		r.Events = r.Events[:n]
+	} else {
+		r.Events = r.Events[len(r.Events)-n:]
+	}
+	return r
+}
+
+func (r report) headline() string {
+	headline := fmt.Sprintf("Project: %s | %s | %d tasks", r.Project, r.Period, r.Total)
+	if len(r.Events) < r.Total {
+		headline += fmt.Sprintf(" (showing %d)", len(r.Events))
+	}
+	if r.Estimate != "" {
+		headline += fmt.Sprintf(" | estimated %s", r.Estimate)
+	}
+	return headline
+}
+
+func renderReport(w io.Writer, r report, format string, opts renderOptions) error {
+	switch format {
+	case formatJSON:
+		return renderJSON(w, r)
+	case formatJSONL:
+		return renderJSONL(w, r.Events)
+	case formatCSV:
+		// CSV はそのまま他のツールで読み込めるように、ヘッダー行以外は出力しない
+		return renderCSV(w, r.Events, opts)
+	case formatICS:
+		return renderICS(w, r)
+	case formatHTML:
+		return renderHTML(w, newHTMLReport(r))
+	case formatPrometheus:
+		return renderPrometheus(w, r, opts.daily)
+	case formatHeatmap:
+		return renderHeatmap(w, r, opts.heatmap)
+	case formatDigest:
+		return renderDigest(w, r)
+	}
+
+	if err := renderHeadline(w, r, format); err != nil {
+		return err
+	}
+	return renderEvents(w, r.Events, format, opts)
+}
+
+// レポートの先頭にプロジェクト・期間・件数の見出しを出力する
+func renderHeadline(w io.Writer, r report, format string) error {
+	var headline string
+	switch format {
+	case formatMarkdown, formatChecklist:
+		headline = fmt.Sprintf("# %s\n\n", escapeMarkdown(r.headline()))
+	default:
+		headline = r.headline() + "\n\n"
+	}
+	if _, err := io.WriteString(w, headline); err != nil {
+		return fmt.Errorf("write error: %w", err)
+	}
+	return nil
+}
+
+// 見出しを除いたイベントの一覧を出力する。グループごとに出力する場合にも使う
+func renderEvents(w io.Writer, events []Event, format string, opts renderOptions) error {
+	switch format {
+	case formatText:
+		return renderText(w, events, opts)
+	case formatMarkdown:
+		return renderMarkdown(w, events, opts)
+	case formatChecklist:
+		return renderChecklist(w, events, opts)
+	default:
+		return fmt.Errorf("format %q can not render events without a report", format)
+	}
+}
+
+func renderText(w io.Writer, events []Event, opts renderOptions) error {
+	columns := textColumns(events, opts)
+	for _, event := range events {
+		if _, err := fmt.Fprintln(w, textLine(event, columns)); err != nil {
+			return fmt.Errorf("write error: %w", err)
+		}
+		for _, line := range noteLines(event, "") {
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return fmt.Errorf("write error: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func renderJSON(w io.Writer, r report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		return fmt.Errorf("json encode error: %w", err)
+	}
+	return nil
+}
+
+// 1行に1イベントの JSON を出力する。見出しは出力しない
+// json.Encoder は Encode ごとに w に書き込むので、w が標準出力ならそのまま1件ずつ出力される
+func renderJSONL(w io.Writer, events []Event) error {
+	enc := json.NewEncoder(w)
+	for _, event := range events {
+		if err := enc.Encode(event); err != nil {
+			return fmt.Errorf("json encode error: %w", err)
+		}
+	}
+	return nil
+}
+
+func renderCSV(w io.Writer, events []Event, opts renderOptions) error {
+	columns := tableColumns(events, opts)
+
+	cw := csv.NewWriter(w)
+	header := make([]string, 0, len(columns))
+	for _, c := range columns {
+		header = append(header, c.name)
+	}
+	if err := cw.Write(header); err != nil {
+		return fmt.Errorf("csv write error: %w", err)
+	}
+	for _, event := range events {
+		record := make([]string, 0, len(columns))
+		for _, c := range columns {
+			record = append(record, c.value(event))
+		}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("csv write error: %w", err)
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("csv flush error: %w", err)
+	}
+	return nil
+}
+
+func renderMarkdown(w io.Writer, events []Event, opts renderOptions) error {
+	columns := tableColumns(events, opts)
+
+	var buf strings.Builder
+	for _, c := range columns {
+		buf.WriteString("| " + c.title + " ")
+	}
+	buf.WriteString("|\n")
+	buf.WriteString(strings.Repeat("| --- ", len(columns)) + "|\n")
+	for _, event := range events {
+		for _, c := range columns {
+			cell := escapeMarkdownCell(c.value(event))
+			if c.name == contentColumn.name {
+				cell += markdownNotes(event)
+			}
+			buf.WriteString("| " + cell + " ")
+		}
+		buf.WriteString("|\n")
+	}
+
+	if _, err := io.WriteString(w, buf.String()); err != nil {
+		return fmt.Errorf("write error: %w", err)
+	}
+	return nil
+}
+
+// タスクの内容を task #<タスクID> に置き換えたイベントを返す。元のイベントは変更しない
+// 日時・件数はそのままにして、タスクの内容を公開せずにレポートを共有できるようにする
+func redactContents(events []Event) []Event {
+	result := make([]Event, len(events))
+	for i, event := range events {
+		id := event.TaskID
+		if id == "" {
+			id = event.ID
+		}
+		event.Content = fmt.Sprintf("task #%s", id)
+		if event.ParentContent != "" {
+			event.ParentContent = fmt.Sprintf("task #%s", event.ParentTaskID)
+		}
+		event.Notes = nil
+		result[i] = event
+	}
+	return result
+}
+
+// タスクの内容を maxWidth 文字（バイト数ではなくルーン数）までに切り詰めたイベントを返す。元のイベントは変更しない
+// 切り詰めた場合は末尾を … にして、… を含めて maxWidth 文字にする
+func truncateContents(events []Event, maxWidth int) []Event {
+	result := make([]Event, len(events))
+	for i, event := range events {
+		event.Content = truncateRunes(event.Content, maxWidth)
+		event.ParentContent = truncateRunes(event.ParentContent, maxWidth)
+		result[i] = event
+	}
+	return result
+}
+
+func truncateRunes(s string, maxWidth int) string {
+	if maxWidth <= 0 || utf8.RuneCountInString(s) <= maxWidth {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:maxWidth-1]) + "…"
+}
+
+// テーブルのセル内で列区切りや改行として解釈されないようにエスケープする
+func escapeMarkdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	s = strings.ReplaceAll(s, "\r\n", " ")
+	s = strings.ReplaceAll(s, "\n", " ")
+	return s
+}
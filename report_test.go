@@ -0,0 +1,13 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReportDateLayoutSeconds(t *testing.T) {
+	at := time.Date(2023, 1, 28, 13, 14, 28, 0, time.UTC)
+	if got, want := at.Format(reportDateLayout), "2023/01/28 13:14:28"; got != want {
+		t.Errorf("Format(reportDateLayout) = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"time"
+)
+
+var (
+	lastDueDateColumn = column{name: "last_due_date", title: "Previous due", value: func(e Event) string { return formatDueDate(e.LastDueDate) }}
+	dueDateColumn     = column{name: "due_date", title: "Due", value: func(e Event) string { return formatDueDate(e.DueDate) }}
+)
+
+func formatDueDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(dateLayout)
+}
+
+// 期限が変更されていたイベントだけを返す
+// last_due_date が記録されていないイベントは変更されていないものとして扱う
+func rescheduledEvents(events []Event) []Event {
+	var result []Event
+	for _, event := range events {
+		if event.LastDueDate == nil {
+			continue
+		}
+		if event.DueDate != nil && event.DueDate.Equal(*event.LastDueDate) {
+			continue
+		}
+		result = append(result, event)
+	}
+	return result
+}
@@ -0,0 +1,1018 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"todoistreport/todoist"
+)
+
+// errNoEvents は --fail-if-empty を指定して、対象のイベントが0件だった場合のエラー
+var errNoEvents = errors.New("no events found")
+
+// inputError は API にリクエストを送る前の、フラグ（--serve ではクエリパラメータ）の値や組み合わせの誤りによるエラー
+type inputError struct {
+	err error
+}
+
+func (e *inputError) Error() string {
+	return e.err.Error()
+}
+
+func (e *inputError) Unwrap() error {
+	return e.err
+}
+
+func run(ctx context.Context, cfg config) (err error) {
+	// 値の確認と期間の計算が終わるまでに返したエラーは inputError にする
+	validating := true
+	defer func() {
+		if err != nil && validating {
+			err = &inputError{err: err}
+		}
+	}()
+
+	if len(cfg.accounts) == 0 {
+		return todoist.ErrNoAPIToken
+	}
+	if err := validateFormat(cfg.format); err != nil {
+		return err
+	}
+	// json, csv の後ろに集計を出力すると壊れたデータになってしまうので組み合わせを許可しない
+	// prometheus は日ごとの完了数をメトリクスとして出力する
+	if cfg.summary && cfg.format != formatText && cfg.format != formatMarkdown && cfg.format != formatPrometheus {
+		return fmt.Errorf("--summary is not supported with format %q", cfg.format)
+	}
+	if cfg.top < 0 {
+		return fmt.Errorf("--top must be 0 or greater, got %d", cfg.top)
+	}
+	if cfg.top > 0 && !formatIn(cfg.format, formatText, formatMarkdown) {
+		return fmt.Errorf("--top is not supported with format %q", cfg.format)
+	}
+	if cfg.compare != "" && !formatIn(cfg.format, formatText, formatMarkdown) {
+		return fmt.Errorf("--compare is not supported with format %q", cfg.format)
+	}
+	if cfg.completionRate && !formatIn(cfg.format, formatText, formatMarkdown) {
+		return fmt.Errorf("--completion-rate is not supported with format %q", cfg.format)
+	}
+	if cfg.labels && cfg.format != formatText && cfg.format != formatMarkdown {
+		return fmt.Errorf("--labels is not supported with format %q", cfg.format)
+	}
+	if cfg.clients && cfg.format != formatText && cfg.format != formatMarkdown {
+		return fmt.Errorf("--clients is not supported with format %q", cfg.format)
+	}
+	// JSON は他のツールで使うデータなので、--relative を指定しても ISO 8601 の日時のまま出力する
+	if cfg.relative && !formatIn(cfg.format, formatText, formatMarkdown, formatCSV, formatChecklist, formatJSON, formatJSONL) {
+		return fmt.Errorf("--relative is not supported with format %q", cfg.format)
+	}
+	if cfg.withNotes && !formatIn(cfg.format, formatText, formatMarkdown, formatJSON, formatJSONL) {
+		return fmt.Errorf("--with-notes is not supported with format %q", cfg.format)
+	}
+	if cfg.showID && !formatIn(cfg.format, formatText, formatMarkdown, formatCSV, formatJSON, formatJSONL) {
+		return fmt.Errorf("--show-id is not supported with format %q", cfg.format)
+	}
+	if cfg.showClient && !formatIn(cfg.format, formatText, formatMarkdown, formatCSV, formatJSON, formatJSONL) {
+		return fmt.Errorf("--show-client is not supported with format %q", cfg.format)
+	}
+	if cfg.rescheduled && !formatIn(cfg.format, formatText, formatMarkdown, formatCSV, formatJSON, formatJSONL) {
+		return fmt.Errorf("--rescheduled is not supported with format %q", cfg.format)
+	}
+	// 親タスク・ラベルはアカウントごとに取得する必要があるので、複数のアカウントでは使えない
+	if (cfg.tree || cfg.labels) && len(cfg.accounts) > 1 {
+		return errors.New("--tree and --labels are not supported with multiple --token")
+	}
+	fields, err := parseFields(cfg.fields)
+	if err != nil {
+		return err
+	}
+	if len(fields) > 0 && !formatIn(cfg.format, formatText, formatCSV, formatMarkdown) {
+		return fmt.Errorf("--fields is not supported with format %q", cfg.format)
+	}
+	// テンプレートでレポート全体の形を決めるので、一覧の出力方法を変えるオプションとは組み合わせない
+	var reportTmpl *reportTemplate
+	if cfg.templateFile != "" {
+		if cfg.format != formatText {
+			return fmt.Errorf("--template-file is not supported with format %q", cfg.format)
+		}
+		if cfg.tree || cfg.groupBy != groupByNone || len(fields) > 0 || cfg.relative {
+			return errors.New("--template-file can not be used with --tree, --group-by, --fields or --relative")
+		}
+		reportTmpl, err = loadReportTemplate(cfg.templateFile)
+		if err != nil {
+			return err
+		}
+	}
+	if cfg.collapse && !formatIn(cfg.format, formatText, formatMarkdown, formatCSV, formatJSON, formatJSONL) {
+		return fmt.Errorf("--collapse-recurring is not supported with format %q", cfg.format)
+	}
+	if cfg.collapse && cfg.tree {
+		return errors.New("--collapse-recurring can not be used with --tree")
+	}
+	if cfg.tree && cfg.format != formatText {
+		return fmt.Errorf("--tree is not supported with format %q", cfg.format)
+	}
+	// 正規表現の誤りは API にリクエストする前に知らせる
+	var grepPattern *regexp.Regexp
+	if cfg.grep != "" {
+		grepPattern, err = compileGrepPattern(cfg.grep, cfg.caseSensitive)
+		if err != nil {
+			return err
+		}
+	}
+	var estimatePattern *regexp.Regexp
+	if cfg.parseEstimates {
+		estimatePattern, err = compileEstimatePattern(cfg.estimatePattern)
+		if err != nil {
+			return err
+		}
+	}
+	if err := validateOrder(cfg.order); err != nil {
+		return err
+	}
+	if cfg.maxContentWidth < 0 {
+		return fmt.Errorf("--max-content-width must be 0 or greater, got %d", cfg.maxContentWidth)
+	}
+	if cfg.limit < 0 {
+		return fmt.Errorf("--limit must be 0 or greater, got %d", cfg.limit)
+	}
+	// ヒートマップ・ダイジェストは期間の全ての日の件数を出力するので、件数を絞り込めない
+	if cfg.limit > 0 && formatIn(cfg.format, formatHeatmap, formatDigest) {
+		return fmt.Errorf("--limit is not supported with format %q", cfg.format)
+	}
+	if err := validateGroupBy(cfg.groupBy); err != nil {
+		return err
+	}
+	if err := validateColor(cfg.color); err != nil {
+		return err
+	}
+	if err := validateGroupSort(cfg.groupSort); err != nil {
+		return err
+	}
+	weekStart, err := parseWeekStart(cfg.weekStart)
+	if err != nil {
+		return err
+	}
+	if cfg.groupBy != groupByNone && cfg.format != formatText && cfg.format != formatMarkdown {
+		return fmt.Errorf("--group-by is not supported with format %q", cfg.format)
+	}
+	// 小計と全体の合計を出力するので、一部のイベントだけにすると見出しの件数と合わなくなる
+	if cfg.limit > 0 && (cfg.groupBy == groupByProject || cfg.groupBy == groupByMonth) {
+		return fmt.Errorf("--limit can not be used with --group-by %s", cfg.groupBy)
+	}
+
+	eventTypes := splitList(cfg.eventTypes)
+	for _, eventType := range eventTypes {
+		if err := todoist.ValidateEventType(eventType); err != nil {
+			return err
+		}
+	}
+
+	if err := validateAPI(cfg.api, eventTypes); err != nil {
+		return err
+	}
+	// 追加したタスクは REST API では取得できない
+	if cfg.completionRate && cfg.api != apiSync {
+		return fmt.Errorf("--completion-rate is supported only with --api %s", apiSync)
+	}
+	if cfg.completionRate && !containsString(eventTypes, "completed") {
+		return fmt.Errorf("--completion-rate requires completed in --event-type")
+	}
+	if cfg.raw && cfg.api != apiSync {
+		return fmt.Errorf("--raw is supported only with --api %s", apiSync)
+	}
+	if err := validateBaseURL(cfg.baseURL); err != nil {
+		return err
+	}
+	if err := validateOAuth(cfg); err != nil {
+		return err
+	}
+	if err := validateRPS(cfg.rps); err != nil {
+		return err
+	}
+	if cfg.slackWebhook != "" {
+		if err := validateSlackWebhook(cfg.slackWebhook); err != nil {
+			return err
+		}
+		if cfg.countOnly {
+			return errors.New("--slack-webhook can not be used with --count-only")
+		}
+		if cfg.raw {
+			return errors.New("--slack-webhook can not be used with --raw")
+		}
+	}
+
+	// todoistは UTC で返してくるので、指定したタイムゾーンで日/月をまたいだかを判定する
+	loc, err := loadLocation(cfg.tz)
+	if err != nil {
+		return err
+	}
+
+	// todoistのアクティビティログは、今週を0ページ目として取得する必要があるため
+	// 指定した期間が、何ページ目か何ページ目までなのかを計算する
+	now := cfg.currentTime()
+	var reportPeriod period
+	var startPage, endPage int
+	if cfg.sinceDate != "" || cfg.untilDate != "" {
+		p, err := parseDateRange(cfg.sinceDate, cfg.untilDate, now, loc)
+		if err != nil {
+			return err
+		}
+		reportPeriod = p
+		startPage, endPage = pageRangeForPeriod(now, p, loc)
+	} else {
+		// 省略した場合は実行した時点の今月にする。--serve ではリクエストごとに決める
+		target := cfg.target
+		if target == "" {
+			target = now.In(loc).Format(monthLayout)
+		}
+		p, err := parseTarget(target, loc)
+		if err != nil {
+			return err
+		}
+		reportPeriod = p
+		if p.isYear() {
+			startPage, endPage = pageRangeForPeriod(now, p, loc)
+		} else {
+			startPage, endPage = computePageRange(now, p.since, loc)
+		}
+	}
+	// 年単位のレポートは、他のまとめ方を指定しない場合は月ごとにまとめて小計を出力する
+	// --limit を指定した場合は小計が合わなくなるので、まとめずに一覧で出力する
+	if reportPeriod.isYear() && cfg.groupBy == groupByNone && !cfg.tree && cfg.templateFile == "" && cfg.limit == 0 && formatIn(cfg.format, formatText, formatMarkdown) {
+		cfg.groupBy = groupByMonth
+	}
+
+	// --since-last-run は今までを対象にするので、期間の終わりは指定できない
+	if cfg.sinceLastRun && cfg.untilDate != "" {
+		return errors.New("--since-last-run can not be used with --until")
+	}
+	if cfg.tee && cfg.output == "" {
+		return errors.New("--tee requires --output")
+	}
+	if cfg.api == apiSync {
+		if err := checkMaxPages(reportPeriod, endPage, cfg.maxPages); err != nil {
+			return err
+		}
+		warnRetention(reportPeriod, endPage, cfg.retentionWeeks)
+	}
+
+	// 比較する月も同じプロジェクト・同じ条件で取得する
+	var compareScope *fetchScope
+	if cfg.compare != "" {
+		compareDate, err := time.ParseInLocation(monthLayout, cfg.compare, loc)
+		if err != nil {
+			return fmt.Errorf("compare parse error: %w", err)
+		}
+		compareStart, compareEnd := computePageRange(now, compareDate, loc)
+		if cfg.api == apiSync {
+			if err := checkMaxPages(monthPeriod(compareDate), compareEnd, cfg.maxPages); err != nil {
+				return err
+			}
+			warnRetention(monthPeriod(compareDate), compareEnd, cfg.retentionWeeks)
+		}
+		compareScope = &fetchScope{
+			period:     monthPeriod(compareDate),
+			startPage:  compareStart,
+			endPage:    compareEnd,
+			loc:        loc,
+			eventTypes: eventTypes,
+			grep:       grepPattern,
+		}
+	}
+
+	var outputFile string
+	if cfg.output != "" {
+		outputFile, err = outputPath(cfg.output, reportPeriod)
+		if err != nil {
+			return err
+		}
+	}
+
+	validating = false
+
+	debug := newDebugLogger(cfg.verbose, cfg.logFormat)
+	debug.Printf("period=%s..%s pages=%d..%d", reportPeriod.since.Format(time.RFC3339), reportPeriod.until.Format(time.RFC3339), startPage, endPage)
+
+	httpClient, err := newHTTPClient(cfg.proxy, cfg.timeout)
+	if err != nil {
+		return err
+	}
+	// 1つの limiter を全てのアカウントの Client で共有するので、アカウントをまたいだ合計が --rps 以下になる
+	var limiter *rateLimiter
+	if cfg.rps > 0 {
+		limiter = newRateLimiter(cfg.rps)
+	}
+
+	var cache *projectCache
+	var pageCache *activityCache
+	// 別のベース URL（モックサーバーなど）のレスポンスは、本物の API のキャッシュと混ぜない
+	if !cfg.noCache && cfg.baseURL == todoist.DefaultBaseURL {
+		cache, err = newProjectCache(cfg.projectCacheTTL)
+		if err != nil {
+			log.Println(err)
+		}
+		pageCache, err = newActivityCache(now, loc)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+
+	scope := fetchScope{
+		pageCache:  pageCache,
+		period:     reportPeriod,
+		startPage:  startPage,
+		endPage:    endPage,
+		loc:        loc,
+		eventTypes: eventTypes,
+		grep:       grepPattern,
+		withNotes:  cfg.withNotes && !cfg.countOnly,
+	}
+	names := splitList(cfg.projectName)
+
+	// プロジェクトの ID はアカウントごとに異なるので、プロジェクトの検索もアカウントごとに行う
+	var accounts []resolvedAccount
+	var projects []todoist.Project
+	var client *todoist.Client
+	foundNames := make(map[string]bool)
+	for _, acc := range cfg.accounts {
+		ra, err := resolveAccount(ctx, cfg, acc, names, httpClient, limiter, cache, debug)
+		if err != nil {
+			return err
+		}
+		for _, name := range ra.foundNames {
+			foundNames[name] = true
+		}
+		if len(ra.projects) > 0 {
+			accounts = append(accounts, ra)
+			projects = append(projects, ra.projects...)
+		}
+		client = ra.client
+	}
+
+	// 一部のプロジェクトが見つからなくても、見つかったプロジェクトだけでレポートを出力する
+	for _, name := range names {
+		if !foundNames[name] {
+			log.Printf("project not exists: %q\n", name)
+		}
+	}
+	if len(projects) == 0 {
+		return fmt.Errorf("no projects found: %w", todoist.ErrProjectNotFound)
+	}
+
+	// 前回の実行日時から今までを取得する。前回の実行日時は見つかったプロジェクトごとに違うので、ここで期間を決め直す
+	var lastRun *lastRunState
+	if cfg.sinceLastRun {
+		lastRun, err = newLastRunState()
+		if err != nil {
+			return err
+		}
+		markers, err := lastRun.load()
+		if err != nil {
+			return err
+		}
+		reportPeriod = period{since: lastRunSince(markers, accounts, reportPeriod.since).In(loc), until: now}
+		startPage, endPage = pageRangeForPeriod(now, reportPeriod, loc)
+		if cfg.api == apiSync {
+			if err := checkMaxPages(reportPeriod, endPage, cfg.maxPages); err != nil {
+				return err
+			}
+		}
+		debug.Printf("since-last-run period=%s..%s pages=%d..%d", reportPeriod.since.Format(time.RFC3339), reportPeriod.until.Format(time.RFC3339), startPage, endPage)
+		scope.period = reportPeriod
+		scope.startPage = startPage
+		scope.endPage = endPage
+		scope.lastRun = markers
+	}
+
+	if cfg.dryRun {
+		for _, ra := range accounts {
+			if err := renderPlan(cfg.stdout, fetchPlan{
+				account:    ra.label,
+				api:        cfg.api,
+				projects:   ra.projects,
+				startPage:  startPage,
+				endPage:    endPage,
+				period:     reportPeriod,
+				loc:        loc,
+				eventTypes: eventTypes,
+				sharing:    cfg.sharing,
+				format:     cfg.format,
+				slack:      cfg.slackWebhook != "",
+			}); err != nil {
+				return err
+			}
+		}
+		if cfg.exec != "" {
+			if _, err := fmt.Fprintf(cfg.stdout, "exec (not run): %s\n", cfg.exec); err != nil {
+				return fmt.Errorf("write error: %w", err)
+			}
+		}
+		// --slack-webhook を指定した場合は、投稿する内容を確認できるようにイベントを取得する
+		if cfg.slackWebhook == "" {
+			return nil
+		}
+	}
+
+	// レスポンスをそのまま出力するので、イベントへの変換や絞り込みはしない
+	if cfg.raw {
+		var bodies []json.RawMessage
+		for _, ra := range accounts {
+			accountBodies, err := fetchRawPages(ctx, ra.client, pageRequests(ra.projects, startPage, endPage), eventTypes)
+			if err != nil {
+				return err
+			}
+			bodies = append(bodies, accountBodies...)
+		}
+		data := rawArray(bodies)
+		if outputFile != "" {
+			return writeOutputFile(outputFile, data, nil)
+		}
+		if _, err := cfg.stdout.Write(data); err != nil {
+			return fmt.Errorf("write error: %w", err)
+		}
+		return nil
+	}
+
+	// 大きなレポートでも全てのイベントをメモリに溜めないように、jsonl はページを取得するごとに書き込む
+	if canStreamJSONL(cfg, outputFile) {
+		total, err := streamJSONL(ctx, cfg.stdout, cfg, accounts, scope, now, debug)
+		if err != nil {
+			return err
+		}
+		return finishRun(cfg, lastRun, accounts, now, total)
+	}
+
+	events, err := fetchAccountsEvents(ctx, cfg, accounts, scope, debug)
+	if err != nil {
+		return err
+	}
+	var compareEvents []Event
+	if compareScope != nil {
+		compareScope.pageCache = pageCache
+		compareEvents, err = fetchAccountsEvents(ctx, cfg, accounts, *compareScope, debug)
+		if err != nil {
+			return err
+		}
+	}
+	var addedEvents []Event
+	if cfg.completionRate {
+		// 同じ期間の added イベントを取得する。--rescheduled の絞り込みは完了したタスク向けなので使わない
+		addedScope := scope
+		addedScope.eventTypes = []string{"added"}
+		addedScope.withNotes = false
+		addedCfg := cfg
+		addedCfg.rescheduled = false
+		addedEvents, err = fetchAccountsEvents(ctx, addedCfg, accounts, addedScope, debug)
+		if err != nil {
+			return err
+		}
+	}
+	sortEvents(events, cfg.order)
+
+	// 一覧や集計は出力せず、絞り込んだ後の件数だけを出力する
+	if cfg.countOnly {
+		data := []byte(strconv.Itoa(len(events)) + "\n")
+		if err := writeReport(cfg, outputFile, data); err != nil {
+			return err
+		}
+		if err := runExec(ctx, cfg, data, len(events), reportPeriod, projectsLabel(projects), outputFile); err != nil {
+			return err
+		}
+		return finishRun(cfg, lastRun, accounts, now, len(events))
+	}
+
+	// 集計（--summary など）は絞り込む前の全てのイベントを対象にする
+	rep := newReport(projectsLabel(projects), reportPeriod, events).limit(cfg.limit, cfg.order)
+	for _, project := range projects {
+		if project.ID != "" {
+			rep.projects = append(rep.projects, project.Name)
+		}
+	}
+	rep.eventTypes = eventTypes
+	if estimatePattern != nil {
+		rep.Estimate = formatEstimate(sumEstimates(events, estimatePattern))
+	}
+	if cfg.tree {
+		if err := resolveParentContents(ctx, client, rep.Events); err != nil {
+			return err
+		}
+	}
+	// JSON では1件ずつのイベントをそのまま出力する
+	if cfg.collapse && !formatIn(cfg.format, formatJSON, formatJSONL) {
+		rep.Events = collapseRecurring(rep.Events)
+	}
+	if cfg.redact {
+		rep.Events = redactContents(rep.Events)
+	}
+	// JSON は他のツールで使うデータなので切り詰めない
+	if cfg.maxContentWidth > 0 && !formatIn(cfg.format, formatJSON, formatJSONL) {
+		rep.Events = truncateContents(rep.Events, cfg.maxContentWidth)
+	}
+
+	if !cfg.showID && formatIn(cfg.format, formatJSON, formatJSONL) {
+		rep.Events = withoutIDs(rep.Events)
+	}
+
+	opts := renderOptions{fields: fields, showClient: cfg.showClient, showDueDates: cfg.rescheduled, showID: cfg.showID, daily: cfg.summary}
+	if cfg.relative {
+		opts.relative = now
+	}
+	// --output で書き込むファイルは端末ではないので、auto では色を付けない
+	var colorOut io.Writer = cfg.stdout
+	if outputFile != "" {
+		colorOut = nil
+	}
+	opts.heatmap = heatmapOptions{
+		color:     useColor(cfg.color, colorOut),
+		width:     terminalWidth(),
+		now:       now,
+		weekStart: weekStart,
+	}
+
+	// --dry-run ではレポートを書き込まず、Slack にも投稿せずに投稿する内容だけを表示する
+	if cfg.dryRun {
+		return renderSlackPayload(cfg.stdout, newSlackPayload(rep, opts))
+	}
+
+	// 途中で失敗したときに中途半端なファイルが残らないように、最後にまとめて書き込む
+	var buf bytes.Buffer
+	if reportTmpl != nil {
+		if err := renderTemplate(&buf, reportTmpl, rep); err != nil {
+			return err
+		}
+	} else if cfg.tree || cfg.groupBy != groupByNone {
+		render := func(w io.Writer, events []Event) error {
+			return renderEvents(w, events, cfg.format, opts)
+		}
+		if cfg.tree {
+			render = func(w io.Writer, events []Event) error {
+				return renderTree(w, events, opts)
+			}
+		}
+
+		if err := renderHeadline(&buf, rep, cfg.format); err != nil {
+			return err
+		}
+		if cfg.groupBy == groupByProject || cfg.groupBy == groupByMonth {
+			groups := projectGroups(rep.Events, rep.projects, cfg.groupSort, cfg.showEmpty)
+			if cfg.groupBy == groupByMonth {
+				groups = monthGroups(rep.Events)
+			}
+			if err := renderGroups(&buf, groups, cfg.format, render); err != nil {
+				return err
+			}
+			if err := renderGrandTotal(&buf, len(rep.Events), cfg.format); err != nil {
+				return err
+			}
+		} else if cfg.groupBy != groupByNone {
+			if err := renderGroups(&buf, groupEvents(rep.Events, cfg.groupBy, weekStart), cfg.format, render); err != nil {
+				return err
+			}
+		} else if err := render(&buf, rep.Events); err != nil {
+			return err
+		}
+	} else if err := renderReport(&buf, rep, cfg.format, opts); err != nil {
+		return err
+	}
+
+	if cfg.summary && cfg.format != formatPrometheus {
+		if err := renderSummary(&buf, countByDay(events, reportPeriod), cfg.format, !cfg.noUnicode, now); err != nil {
+			return err
+		}
+	}
+
+	if cfg.labels {
+		itemLabels, labels, err := fetchItemLabels(ctx, client)
+		if err != nil {
+			return err
+		}
+		if err := renderLabelSummary(&buf, countByLabel(events, itemLabels, labels), cfg.format); err != nil {
+			return err
+		}
+	}
+
+	if compareScope != nil {
+		current := newPeriodStats(reportPeriod, len(events), now)
+		previous := newPeriodStats(compareScope.period, len(compareEvents), now)
+		if err := renderComparison(&buf, current, previous, cfg.format); err != nil {
+			return err
+		}
+	}
+
+	if cfg.completionRate {
+		if err := renderCompletionRate(&buf, newCompletionRate(addedEvents, events), cfg.format); err != nil {
+			return err
+		}
+	}
+
+	if cfg.top > 0 {
+		topEvents := events
+		if cfg.redact {
+			topEvents = redactContents(events)
+		}
+		if err := renderTopContents(&buf, topContents(topEvents, cfg.top, cfg.topIgnoreCase), cfg.format); err != nil {
+			return err
+		}
+	}
+
+	if cfg.clients {
+		if err := renderClientSummary(&buf, countByClient(events), cfg.format); err != nil {
+			return err
+		}
+	}
+
+	if err := writeReport(cfg, outputFile, buf.Bytes()); err != nil {
+		return err
+	}
+	if cfg.slackWebhook != "" {
+		if err := postSlack(ctx, httpClient, cfg.slackWebhook, newSlackPayload(rep, opts)); err != nil {
+			return err
+		}
+	}
+	if err := runExec(ctx, cfg, buf.Bytes(), rep.Total, reportPeriod, rep.Project, outputFile); err != nil {
+		return err
+	}
+	return finishRun(cfg, lastRun, accounts, now, rep.Total)
+}
+
+// レポートを --output のファイル（--tee の場合は標準出力にも）か標準出力に書き込む
+func writeReport(cfg config, outputFile string, data []byte) error {
+	if outputFile != "" {
+		var tee io.Writer
+		if cfg.tee {
+			tee = cfg.stdout
+		}
+		return writeOutputFile(outputFile, data, tee)
+	}
+	if _, err := cfg.stdout.Write(data); err != nil {
+		return fmt.Errorf("write error: %w", err)
+	}
+	return nil
+}
+
+// --exec を指定した場合は、書き込んだレポートを渡してコマンドを実行する
+// コマンドが失敗した場合は、次の --since-last-run で同じ期間をもう一度通知できるように前回の実行日時を更新しない
+func runExec(ctx context.Context, cfg config, data []byte, total int, p period, project, outputFile string) error {
+	if cfg.exec == "" {
+		return nil
+	}
+	return runExecHook(ctx, execHook{
+		command: cfg.exec,
+		total:   total,
+		period:  p,
+		project: project,
+		format:  cfg.format,
+		output:  outputFile,
+	}, data)
+}
+
+// レポートを書き込んだ後の処理。total はレポートの件数
+func finishRun(cfg config, lastRun *lastRunState, accounts []resolvedAccount, now time.Time, total int) error {
+	// 次の --since-last-run はレポートを出力できた場合だけ、今回の実行日時からにする
+	if lastRun != nil {
+		if err := lastRun.save(accounts, now); err != nil {
+			return err
+		}
+	}
+
+	// レポートは出力した上で、スクリプトから0件だったことを判定できるようにする
+	if cfg.failIfEmpty && total == 0 {
+		return errNoEvents
+	}
+	return nil
+}
+
+// fetchScope はアカウントによらない取得対象の期間・ページ・イベント種別と、ページのキャッシュ
+type fetchScope struct {
+	pageCache  *activityCache
+	period     period
+	startPage  int
+	endPage    int
+	loc        *time.Location
+	eventTypes []string
+	// --grep の正規表現。nil の場合は絞り込まない
+	grep *regexp.Regexp
+	// --since-last-run の前回の実行日時。nil の場合は絞り込まない
+	lastRun map[string]time.Time
+	// --with-notes でコメントを付けるか。コメントは一覧にだけ出力するので、レポートの期間の scope だけで取得する
+	withNotes bool
+}
+
+// resolvedAccount はプロジェクトを解決したアカウント
+type resolvedAccount struct {
+	// label は複数のアカウントを指定した場合にイベントに付けるラベル。1つの場合は空
+	label        string
+	token        string
+	client       *todoist.Client
+	projects     []todoist.Project
+	projectsByID map[string]todoist.Project
+	foundNames   []string
+	// --exclude, --no-inbox で除外するプロジェクトの ID
+	excludedIDs map[string]bool
+	// --initiator を指定した場合の、このアカウントのユーザーの ID
+	userID string
+}
+
+// 1つのアカウントの Client を作ってプロジェクトを解決する
+// 指定したプロジェクトがこのアカウントに1つも無い場合は projects が空になる
+func resolveAccount(ctx context.Context, cfg config, acc account, names []string, httpClient *http.Client, limiter *rateLimiter, cache *projectCache, debug *log.Logger) (resolvedAccount, error) {
+	clientOpts := []todoist.Option{
+		todoist.WithHTTPClient(httpClient),
+		todoist.WithMaxRetries(cfg.retries),
+		todoist.WithUserAgent(cfg.userAgent),
+		todoist.WithBaseURL(cfg.baseURL),
+	}
+	// --verbose でない場合はログを捨てるので、未知のフィールドの確認（レスポンスの decode し直し）もしないように渡さない
+	if cfg.verbose {
+		clientOpts = append(clientOpts, todoist.WithLogger(debug))
+	}
+	if cfg.oauth.RefreshToken != "" {
+		clientOpts = append(clientOpts, todoist.WithOAuthRefresh(cfg.oauth))
+	}
+	// nil の *rateLimiter を RateLimiter として渡すと nil ではなくなるので、指定した場合だけ渡す
+	if limiter != nil {
+		clientOpts = append(clientOpts, todoist.WithRateLimiter(limiter))
+	}
+	client := todoist.NewClient(acc.token, clientOpts...)
+	ra := resolvedAccount{token: acc.token, client: client}
+	if len(cfg.accounts) > 1 {
+		ra.label = acc.label
+	}
+
+	// プロジェクト名の解決と、イベントのプロジェクトIDから名前を引くために使う
+	projectsResponse, cached, err := loadProjects(ctx, client, cache, acc.token)
+	if err != nil {
+		return ra, err
+	}
+
+	// プロジェクトを指定しない場合はアカウント全体を対象にする。ID が空の Project はプロジェクトで絞り込まないことを表す
+	projects := []todoist.Project{{}}
+	if len(names) > 0 {
+		opts := todoist.MatchOptions{
+			Substring:       cfg.substring,
+			IncludeArchived: cfg.includeArchived,
+		}
+		found, missing, err := searchProjectsByName(projectsResponse, names, opts)
+		if err != nil {
+			return ra, err
+		}
+		// キャッシュした後に作成されたプロジェクトかもしれないので、差分同期してからもう一度だけ検索する
+		if len(missing) > 0 && cached {
+			debug.Printf("projects not found in cache, refreshing: %q", missing)
+			projectsResponse, err = refreshProjects(ctx, client, cache, acc.token, projectsResponse)
+			if err != nil {
+				return ra, err
+			}
+			found, missing, err = searchProjectsByName(projectsResponse, names, opts)
+			if err != nil {
+				return ra, err
+			}
+		}
+		for _, name := range names {
+			if !containsString(missing, name) {
+				ra.foundNames = append(ra.foundNames, name)
+			}
+		}
+		if cfg.recursive {
+			found = withDescendants(projectsResponse, found, todoist.MatchOptions{IncludeArchived: cfg.includeArchived})
+		}
+		projects = filterProjects(found, cfg.sharing)
+	}
+	ra.projects = cfg.exclude.filter(projects)
+	ra.excludedIDs = cfg.exclude.projectIDs(projectsResponse.Projects)
+	ra.projectsByID = projectMap(projectsResponse.Projects)
+
+	// --initiator で自分を判定するために、scope ごとではなくアカウントごとに1回だけ取得する
+	if cfg.initiator != "" {
+		user, err := client.User(ctx)
+		if err != nil {
+			return ra, fmt.Errorf("get user error: %w", err)
+		}
+		ra.userID = user.ID
+	}
+	return ra, nil
+}
+
+// 全てのアカウントの scope のイベントを取得する
+func fetchAccountsEvents(ctx context.Context, cfg config, accounts []resolvedAccount, scope fetchScope, debug *log.Logger) ([]Event, error) {
+	var events []Event
+	for _, ra := range accounts {
+		accountEvents, err := fetchAccountEvents(ctx, cfg, ra, scope, debug)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, accountEvents...)
+	}
+	return events, nil
+}
+
+// 1つのアカウントの scope のイベントを取得して、期間・共有状態・完了したユーザーなどで絞り込む
+// 複数のアカウントを指定した場合は、イベントにアカウントのラベルを付ける
+func fetchAccountEvents(ctx context.Context, cfg config, ra resolvedAccount, scope fetchScope, debug *log.Logger) ([]Event, error) {
+	var events []Event
+	switch cfg.api {
+	case apiREST:
+		var err error
+		events, err = fetchCompletedEvents(ctx, ra.client, ra.projects, scope.period, ra.projectsByID, scope.loc)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		requests := pageRequests(ra.projects, scope.startPage, scope.endPage)
+		// ログと混ざらないように、--verbose でなく標準エラー出力が端末の場合だけ進み具合を表示する
+		var pageProgress *progress
+		if !cfg.quiet && !cfg.verbose && isTerminal(os.Stderr) {
+			pageProgress = newProgress(os.Stderr, len(requests))
+		}
+		results, err := fetchPages(ctx, ra.client, requests, scope.eventTypes, cfg.concurrency, scope.pageCache, ra.token, pageProgress)
+		if err != nil {
+			return nil, err
+		}
+		events = activityEvents(dedupePageResults(results), ra.projectsByID, scope.loc, debug)
+	}
+	fetched := len(events)
+	events = filterEvents(events, scope.period, ra.projectsByID, cfg.sharing)
+	if scope.lastRun != nil {
+		events = filterLastRun(events, ra, scope.lastRun)
+	}
+	events = filterContent(events, scope.grep)
+	events = filterExcludedProjects(events, ra.excludedIDs)
+	// 置き換えた内容は表示用なので、--grep で絞り込んだ後に置き換える
+	events = fillEmptyContent(events, cfg.skipEmpty)
+	if scope.withNotes {
+		itemNotes, err := fetchItemNotes(ctx, ra.client)
+		if err != nil {
+			return nil, err
+		}
+		events = attachNotes(events, itemNotes)
+	}
+
+	if cfg.initiator != "" {
+		initiatorID := cfg.initiator
+		if initiatorID == initiatorMe {
+			initiatorID = ra.userID
+		}
+		events = filterInitiator(events, initiatorID, ra.userID)
+	}
+	if cfg.rescheduled {
+		events = rescheduledEvents(events)
+	}
+	debug.Printf("account=%q events=%d matched=%d", ra.label, fetched, len(events))
+
+	for i := range events {
+		events[i].Account = ra.label
+	}
+	return events, nil
+}
+
+// 指定したプロジェクトの直後にその子孫のプロジェクトを並べる。複数回含まれるプロジェクトは最初の1つだけにする
+func withDescendants(response todoist.GetProjectsResponse, projects []todoist.Project, opts todoist.MatchOptions) []todoist.Project {
+	var result []todoist.Project
+	seen := make(map[string]bool)
+	for _, project := range projects {
+		for _, p := range append([]todoist.Project{project}, response.Descendants(project.ID, opts)...) {
+			if seen[p.ID] {
+				continue
+			}
+			seen[p.ID] = true
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// 複数のプロジェクト名をまとめて検索する。見つからなかったプロジェクト名は missing で返す
+func searchProjectsByName(response todoist.GetProjectsResponse, projectNames []string, opts todoist.MatchOptions) ([]todoist.Project, []string, error) {
+	var projects []todoist.Project
+	var missing []string
+	for _, name := range projectNames {
+		project, err := response.MatchProject(name, opts)
+		if errors.Is(err, todoist.ErrProjectNotFound) {
+			missing = append(missing, name)
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		projects = append(projects, project)
+	}
+
+	return projects, missing, nil
+}
+
+func projectsLabel(projects []todoist.Project) string {
+	names := make([]string, 0, len(projects))
+	for _, project := range projects {
+		if project.ID == "" {
+			return "All projects"
+		}
+		names = append(names, project.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func projectMap(projects []todoist.Project) map[string]todoist.Project {
+	m := make(map[string]todoist.Project, len(projects))
+	for _, project := range projects {
+		m[project.ID] = project
+	}
+	return m
+}
+
+// アクティビティの後にプロジェクトが削除された場合など、名前が分からないときは ID をそのまま使う
+func projectName(projects map[string]todoist.Project, projectID string) string {
+	if project, ok := projects[projectID]; ok {
+		return project.Name
+	}
+	return projectID
+}
+
+// sharingFilter は共有プロジェクト・個人プロジェクトでの絞り込み条件
+type sharingFilter int
+
+const (
+	sharingAny sharingFilter = iota
+	sharingSharedOnly
+	sharingPersonalOnly
+)
+
+func newSharingFilter(sharedOnly, personalOnly bool) (sharingFilter, error) {
+	switch {
+	case sharedOnly && personalOnly:
+		return sharingAny, errors.New("--shared-only and --personal-only cannot be used together")
+	case sharedOnly:
+		return sharingSharedOnly, nil
+	case personalOnly:
+		return sharingPersonalOnly, nil
+	default:
+		return sharingAny, nil
+	}
+}
+
+func (f sharingFilter) String() string {
+	switch f {
+	case sharingSharedOnly:
+		return "shared only"
+	case sharingPersonalOnly:
+		return "personal only"
+	default:
+		return "all"
+	}
+}
+
+// known が false（削除されたなどで共有状態が分からないプロジェクト）の場合は、絞り込みをしていなければ含める
+func (f sharingFilter) match(project todoist.Project, known bool) bool {
+	switch f {
+	case sharingSharedOnly:
+		return known && project.Shared
+	case sharingPersonalOnly:
+		return known && !project.Shared
+	default:
+		return true
+	}
+}
+
+func filterProjects(projects []todoist.Project, f sharingFilter) []todoist.Project {
+	var result []todoist.Project
+	for _, project := range projects {
+		if !f.match(project, true) {
+			log.Printf("project %q is skipped by the shared/personal filter\n", project.Name)
+			continue
+		}
+		result = append(result, project)
+	}
+	return result
+}
+
+// カンマ区切りの値を分割する。空の要素は取り除く
+func splitList(s string) []string {
+	var values []string
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		values = append(values, v)
+	}
+	return values
+}
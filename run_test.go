@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"todoistreport/todoist"
+)
+
+// フラグのデフォルト値と同じで、API にリクエストを送る前の確認を通る config
+func testConfig() config {
+	return config{
+		accounts:   accountsFlag{{token: "test-token"}},
+		format:     formatText,
+		api:        apiSync,
+		groupBy:    groupByNone,
+		color:      colorNever,
+		baseURL:    todoist.DefaultBaseURL,
+		eventTypes: "completed",
+		order:      orderAsc,
+		groupSort:  groupSortName,
+		weekStart:  weekStartMonday,
+	}
+}
+
+func TestRunRejectsLimitWithGroupTotals(t *testing.T) {
+	for _, groupBy := range []string{groupByProject, groupByMonth} {
+		t.Run(groupBy, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.groupBy = groupBy
+			cfg.limit = 3
+			err := run(context.Background(), cfg)
+			if err == nil || !strings.Contains(err.Error(), "--limit can not be used with --group-by") {
+				t.Errorf("run() error = %v, want --limit can not be used with --group-by", err)
+			}
+		})
+	}
+}
+
+// stubTransport はネットワークに接続せずに、リクエストごとに固定のレスポンスを返す
+type stubTransport func(req *http.Request) (*http.Response, error)
+
+func (f stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// path ごとに body を 200 で返す。知らない path は 404 にする
+func stubAPI(bodies map[string]string) stubTransport {
+	return func(req *http.Request) (*http.Response, error) {
+		res := &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Request:    req,
+		}
+		body, ok := bodies[req.URL.Path]
+		if !ok {
+			res.StatusCode = http.StatusNotFound
+			body = `{"error":"not found"}`
+		}
+		res.Body = io.NopCloser(strings.NewReader(body))
+		return res, nil
+	}
+}
+
+func newStubAccount(bodies map[string]string) resolvedAccount {
+	client := todoist.NewClient("test-token", todoist.WithTransport(stubAPI(bodies)), todoist.WithMaxRetries(0))
+	return resolvedAccount{
+		token:        "test-token",
+		client:       client,
+		projects:     []todoist.Project{{}},
+		projectsByID: map[string]todoist.Project{"1": {ID: "1", Name: "仕事"}},
+	}
+}
+
+// 内容が空のイベントの "(deleted task ...)" は表示用なので、--grep ではマッチしない
+func TestFetchAccountEventsGrepIgnoresPlaceholder(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	ra := newStubAccount(map[string]string{
+		"/sync/v9/activity/get": `{"events":[` +
+			`{"id":1,"object_type":"item","object_id":"101","event_type":"completed","event_date":"2024-03-01T00:00:00Z","parent_project_id":"1","extra_data":{"content":""}},` +
+			`{"id":2,"object_type":"item","object_id":"102","event_type":"completed","event_date":"2024-03-02T00:00:00Z","parent_project_id":"1","extra_data":{"content":"deleted files cleanup"}}` +
+			`],"count":2}`,
+	})
+	cfg := testConfig()
+	cfg.concurrency = 1
+	cfg.quiet = true
+	grep, err := compileGrepPattern("deleted", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scope := fetchScope{
+		period: monthPeriod(time.Date(2024, 3, 1, 0, 0, 0, 0, loc)),
+		loc:    loc,
+		grep:   grep,
+	}
+
+	events, err := fetchAccountEvents(context.Background(), cfg, ra, scope, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("fetchAccountEvents() error = %v", err)
+	}
+	if len(events) != 1 || events[0].Content != "deleted files cleanup" {
+		t.Errorf("fetchAccountEvents() = %+v, want only %q", events, "deleted files cleanup")
+	}
+}
+
+// コメントは --with-notes のレポートの期間の scope だけで取得し、比較や完了率の scope では取得しない
+func TestFetchAccountEventsNotesOnlyForReportScope(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	bodies := map[string]string{
+		"/sync/v9/activity/get": `{"events":[{"id":1,"object_type":"item","object_id":"101","event_type":"completed","event_date":"2024-03-01T00:00:00Z","parent_project_id":"1","extra_data":{"content":"牛乳"}}],"count":1}`,
+		"/sync/v9/sync":         `{"notes":[{"id":"n1","item_id":"101","content":"2本","posted_at":"2024-03-01T00:00:00Z"}],"sync_token":"token1"}`,
+	}
+	syncRequests := 0
+	transport := stubAPI(bodies)
+	ra := newStubAccount(bodies)
+	ra.client = todoist.NewClient("test-token", todoist.WithMaxRetries(0), todoist.WithTransport(stubTransport(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/sync/v9/sync" {
+			syncRequests++
+		}
+		return transport(req)
+	})))
+	cfg := testConfig()
+	cfg.concurrency = 1
+	cfg.quiet = true
+	cfg.withNotes = true
+
+	tests := []struct {
+		name             string
+		withNotes        bool
+		wantNotes        []string
+		wantSyncRequests int
+	}{
+		{name: "report scope", withNotes: true, wantNotes: []string{"2本"}, wantSyncRequests: 1},
+		{name: "compare scope", withNotes: false, wantNotes: nil, wantSyncRequests: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			syncRequests = 0
+			scope := fetchScope{
+				period:    monthPeriod(time.Date(2024, 3, 1, 0, 0, 0, 0, loc)),
+				loc:       loc,
+				withNotes: tt.withNotes,
+			}
+			events, err := fetchAccountEvents(context.Background(), cfg, ra, scope, log.New(io.Discard, "", 0))
+			if err != nil {
+				t.Fatalf("fetchAccountEvents() error = %v", err)
+			}
+			if len(events) != 1 || !reflect.DeepEqual(events[0].Notes, tt.wantNotes) {
+				t.Errorf("fetchAccountEvents() = %+v, want notes %q", events, tt.wantNotes)
+			}
+			if syncRequests != tt.wantSyncRequests {
+				t.Errorf("sync requests = %d, want %d", syncRequests, tt.wantSyncRequests)
+			}
+		})
+	}
+}
+
+// --initiator のユーザーはアカウントを解決するときに1回だけ取得し、scope ごとには取得しない
+func TestResolveAccountUserOnce(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	bodies := map[string]string{
+		"/sync/v9/sync":         `{"projects":[{"id":"1","name":"仕事"}],"user":{"id":"42"},"full_sync":true,"sync_token":"token1"}`,
+		"/sync/v9/activity/get": `{"events":[{"id":1,"object_type":"item","object_id":"101","event_type":"completed","event_date":"2024-03-01T00:00:00Z","parent_project_id":"1","initiator_id":"42","extra_data":{"content":"牛乳"}}],"count":1}`,
+	}
+	userRequests := 0
+	transport := stubAPI(bodies)
+	httpClient := &http.Client{Transport: stubTransport(func(req *http.Request) (*http.Response, error) {
+		if req.Body != nil {
+			body, _ := io.ReadAll(req.Body)
+			if strings.Contains(string(body), `"user"`) {
+				userRequests++
+			}
+		}
+		return transport(req)
+	})}
+	cfg := testConfig()
+	cfg.concurrency = 1
+	cfg.quiet = true
+	cfg.initiator = initiatorMe
+	cfg.retries = 0
+	debug := log.New(io.Discard, "", 0)
+
+	ra, err := resolveAccount(context.Background(), cfg, cfg.accounts[0], nil, httpClient, nil, nil, debug)
+	if err != nil {
+		t.Fatalf("resolveAccount() error = %v", err)
+	}
+	scope := fetchScope{period: monthPeriod(time.Date(2024, 3, 1, 0, 0, 0, 0, loc)), loc: loc}
+	for i := 0; i < 2; i++ {
+		events, err := fetchAccountEvents(context.Background(), cfg, ra, scope, debug)
+		if err != nil {
+			t.Fatalf("fetchAccountEvents() error = %v", err)
+		}
+		if len(events) != 1 {
+			t.Errorf("len(events) = %d, want 1", len(events))
+		}
+	}
+	if userRequests != 1 {
+		t.Errorf("user requests = %d, want 1", userRequests)
+	}
+}
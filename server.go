@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"todoistreport/todoist"
+)
+
+// フォーマットごとのレスポンスの Content-Type
+var contentTypes = map[string]string{
+	formatText:       "text/plain; charset=utf-8",
+	formatJSON:       "application/json; charset=utf-8",
+	formatJSONL:      "application/x-ndjson; charset=utf-8",
+	formatCSV:        "text/csv; charset=utf-8",
+	formatMarkdown:   "text/markdown; charset=utf-8",
+	formatICS:        "text/calendar; charset=utf-8",
+	formatHTML:       "text/html; charset=utf-8",
+	formatChecklist:  "text/markdown; charset=utf-8",
+	formatPrometheus: "text/plain; version=0.0.4; charset=utf-8",
+	formatHeatmap:    "text/plain; charset=utf-8",
+	formatDigest:     "text/plain; charset=utf-8",
+}
+
+// --serve で指定したアドレスで HTTP サーバーを起動する。ctx がキャンセルされたら終了する
+func serve(ctx context.Context, addr string, cfg config) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/report", reportHandler(cfg))
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		log.Printf("listening on %s\n", addr)
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("serve error: %w", err)
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("shutdown error: %w", err)
+		}
+		return nil
+	}
+}
+
+// /report?project=X&target=2024/05&format=json のリクエストで、コマンドと同じレポートを返す
+// 指定しなかったパラメータは起動時のフラグの値を使う
+func reportHandler(base config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		cfg := base
+		query := r.URL.Query()
+		if query.Has("project") {
+			cfg.projectName = query.Get("project")
+		}
+		if query.Has("target") {
+			cfg.target = query.Get("target")
+		}
+		if query.Has("since") {
+			cfg.sinceDate = query.Get("since")
+		}
+		if query.Has("until") {
+			cfg.untilDate = query.Get("until")
+		}
+		if query.Has("format") {
+			cfg.format = query.Get("format")
+		}
+		// ファイルに書き込まずにレスポンスとして返す
+		cfg.output = ""
+		cfg.tee = false
+		cfg.dryRun = false
+		// リクエストごとに前回の実行日時を更新しない
+		cfg.sinceLastRun = false
+		// 0件の場合もエラーにせず、0件のレポートを返す
+		cfg.failIfEmpty = false
+		// 複数のリクエストの進み具合が混ざるので表示しない
+		cfg.quiet = true
+		// リクエストごとにコマンドの実行や Slack への投稿をしない
+		cfg.exec = ""
+		cfg.slackWebhook = ""
+		var buf bytes.Buffer
+		cfg.stdout = &buf
+
+		if err := run(r.Context(), cfg); err != nil {
+			log.Println(err)
+			http.Error(w, err.Error(), statusCode(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", contentTypes[cfg.format])
+		if _, err := buf.WriteTo(w); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+func statusCode(err error) int {
+	if errors.Is(err, todoist.ErrNoAPIToken) || errors.Is(err, todoist.ErrInvalidAPIToken) {
+		return http.StatusUnauthorized
+	}
+	if errors.Is(err, todoist.ErrProjectNotFound) {
+		return http.StatusNotFound
+	}
+	var inputErr *inputError
+	if errors.As(err, &inputErr) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
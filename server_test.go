@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"todoistreport/todoist"
+)
+
+func TestReportHandlerInvalidParameter(t *testing.T) {
+	base := testConfig()
+	tests := []struct {
+		query   string
+		wantErr string
+	}{
+		{query: "target=bad", wantErr: "target parse error"},
+		{query: "format=nope", wantErr: `unknown format "nope"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			reportHandler(base)(rec, httptest.NewRequest(http.MethodGet, "/report?"+tt.query, nil))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+			if body := rec.Body.String(); !strings.Contains(body, tt.wantErr) {
+				t.Errorf("body = %q, want %q", strings.TrimSpace(body), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStatusCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{err: &inputError{err: errors.New("target parse error")}, want: http.StatusBadRequest},
+		{err: &inputError{err: todoist.ErrNoAPIToken}, want: http.StatusUnauthorized},
+		{err: fmt.Errorf("no projects found: %w", todoist.ErrProjectNotFound), want: http.StatusNotFound},
+		{err: errors.New("http request do error"), want: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := statusCode(tt.err); got != tt.want {
+			t.Errorf("statusCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// Slack の Block Kit の制限。section の text は3000文字、header は150文字、1つのメッセージは50ブロックまで
+const (
+	slackSectionLimit = 3000
+	slackHeaderLimit  = 150
+	slackMaxBlocks    = 50
+)
+
+// Slack のエラーのレスポンス（invalid_payload など）は短いので、これ以上はエラーのメッセージに含めない
+const maxSlackErrorBody = 512
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type string    `json:"type"`
+	Text slackText `json:"text"`
+}
+
+// slackPayload は Incoming Webhook に送るメッセージ。text は通知などブロックを表示できない場合に使われる
+type slackPayload struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+// Webhook の URL はそれだけで投稿できる秘密の値なので、エラーやログには含めない
+func validateSlackWebhook(webhookURL string) error {
+	u, err := url.Parse(webhookURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("--slack-webhook must be an http or https url (e.g. https://hooks.slack.com/services/...)")
+	}
+	return nil
+}
+
+// レポートを見出しと、text 形式と同じ行の一覧の Block Kit のメッセージにする
+// section の文字数の制限を超える場合は複数の section に分け、ブロック数の制限を超える分は件数だけを出力する
+func newSlackPayload(r report, opts renderOptions) slackPayload {
+	headline := r.headline()
+	payload := slackPayload{
+		Text:   headline,
+		Blocks: []slackBlock{{Type: "header", Text: slackText{Type: "plain_text", Text: truncateRunes(headline, slackHeaderLimit)}}},
+	}
+
+	columns := textColumns(r.Events, opts)
+	var section strings.Builder
+	flush := func() {
+		if section.Len() > 0 {
+			payload.Blocks = append(payload.Blocks, slackBlock{Type: "section", Text: slackText{Type: "mrkdwn", Text: section.String()}})
+			section.Reset()
+		}
+	}
+	for i, event := range r.Events {
+		line := "• " + escapeSlack(textLine(event, columns))
+		line = truncateRunes(line, slackSectionLimit-1)
+		if utf8.RuneCountInString(section.String())+utf8.RuneCountInString(line)+1 > slackSectionLimit {
+			flush()
+		}
+		// 最後のブロックは残りの件数に使う
+		if len(payload.Blocks) >= slackMaxBlocks-1 {
+			rest := len(r.Events) - i
+			payload.Blocks = append(payload.Blocks, slackBlock{Type: "section", Text: slackText{Type: "mrkdwn", Text: fmt.Sprintf("_…and %s_", plural(rest, "more task"))}})
+			return payload
+		}
+		section.WriteString(line + "\n")
+	}
+	flush()
+	return payload
+}
+
+// mrkdwn でリンクやメンションとして扱われないように、& < > をエスケープする
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func escapeSlack(s string) string {
+	return slackEscaper.Replace(s)
+}
+
+// --dry-run で、投稿する代わりに payload の JSON を出力する
+func renderSlackPayload(w io.Writer, payload slackPayload) error {
+	data, err := json.MarshalIndent(payload, "", "  ")
+	if err != nil {
+		return fmt.Errorf("slack payload marshal error: %w", err)
+	}
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write error: %w", err)
+	}
+	return nil
+}
+
+// payload を Webhook に POST する。2xx 以外のレスポンスは Slack が返した理由と合わせてエラーにする
+func postSlack(ctx context.Context, httpClient *http.Client, webhookURL string, payload slackPayload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("slack payload marshal error: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("slack webhook request error: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		// *url.Error は URL を含むので、原因のエラーだけにする
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("slack webhook post error: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, maxSlackErrorBody))
+		return fmt.Errorf("slack webhook error: status=%d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"todoistreport/todoist"
+)
+
+// イベントの取得元
+const (
+	// Sync API のアクティビティログ。週単位のページで取得する
+	apiSync = "sync"
+	// 完了済みタスク API。期間を指定してカーソルで取得する（完了したタスクのみ）
+	apiREST = "rest"
+)
+
+var apiValues = []string{apiSync, apiREST}
+
+func validateAPI(api string, eventTypes []string) error {
+	switch api {
+	case apiSync:
+		return nil
+	case apiREST:
+		for _, eventType := range eventTypes {
+			if eventType != "completed" {
+				return fmt.Errorf("--api %s supports only completed events, got %q", apiREST, eventType)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown api %q (available: %s)", api, strings.Join(apiValues, ", "))
+	}
+}
+
+// todoist.Event をレポート用の Event に変換する
+func newEvent(e todoist.Event, projectsByID map[string]todoist.Project, loc *time.Location) Event {
+	return Event{
+		Date:         e.Date.In(loc),
+		Content:      e.Content,
+		Project:      projectName(projectsByID, e.ProjectID),
+		ProjectID:    e.ProjectID,
+		EventType:    e.EventType,
+		Client:       e.Client,
+		ID:           e.ID,
+		TaskID:       e.TaskID,
+		InitiatorID:  e.InitiatorID,
+		ParentTaskID: e.ParentTaskID,
+		DueDate:      inLocation(e.DueDate, loc),
+		LastDueDate:  inLocation(e.LastDueDate, loc),
+	}
+}
+
+func inLocation(t *time.Time, loc *time.Location) *time.Time {
+	if t == nil {
+		return nil
+	}
+	local := t.In(loc)
+	return &local
+}
+
+// アクティビティログのイベントをレポート用の Event に変換する
+func activityEvents(results []pageResult, projectsByID map[string]todoist.Project, loc *time.Location, debug *log.Logger) []Event {
+	var events []Event
+	for _, result := range results {
+		for _, event := range result.Response.ToEvents() {
+			events = append(events, newEvent(event, projectsByID, loc))
+		}
+		debug.Printf("project_id=%q page=%d events=%d", result.Project.ID, result.Page, len(result.Response.Events))
+	}
+	return events
+}
+
+// 完了済みタスク API で一度に指定できる期間の最大の月数
+const completedItemsMaxMonths = 3
+
+// 完了済みタスク API からプロジェクトごとに期間内の完了したタスクを取得して Event に変換する
+// --target YYYY のように3ヶ月より長い期間は、3ヶ月ずつに分けて取得する
+func fetchCompletedEvents(ctx context.Context, client *todoist.Client, projects []todoist.Project, p period, projectsByID map[string]todoist.Project, loc *time.Location) ([]Event, error) {
+	var events []Event
+	for _, project := range projects {
+		// 区切りの時刻に完了したタスクが両方の期間で返ってきても1件にする
+		seen := make(map[string]bool)
+		for _, chunk := range splitPeriod(p, completedItemsMaxMonths) {
+			items, err := client.CompletedItemsAll(ctx, todoist.CompletedItemsOptions{
+				ProjectID: project.ID,
+				Since:     chunk.since,
+				Until:     chunk.until,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			for _, item := range items {
+				event := item.Normalize()
+				if seen[event.ID] {
+					continue
+				}
+				seen[event.ID] = true
+				events = append(events, newEvent(event, projectsByID, loc))
+			}
+		}
+	}
+	return events, nil
+}
+
+// 自分が完了したイベントを --initiator で絞り込むときの値
+const initiatorMe = "me"
+
+// タスクを完了したユーザーで絞り込む。initiatorID が空の場合は絞り込まない
+// 個人プロジェクトなどで initiator_id が記録されていないイベントは、自分（selfID）が完了したものとして扱う
+func filterInitiator(events []Event, initiatorID, selfID string) []Event {
+	if initiatorID == "" {
+		return events
+	}
+	var result []Event
+	for _, event := range events {
+		id := event.InitiatorID
+		if id == "" {
+			id = selfID
+		}
+		if id == initiatorID {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+// 期間とプロジェクトの共有状態でイベントを絞り込む
+func filterEvents(events []Event, p period, projectsByID map[string]todoist.Project, sharing sharingFilter) []Event {
+	var result []Event
+	for _, event := range events {
+		if !p.contains(event.Date) {
+			continue
+		}
+		// アカウント全体を対象にした場合もプロジェクトの共有状態で絞り込む
+		if project, known := projectsByID[event.ProjectID]; !sharing.match(project, known) {
+			continue
+		}
+		result = append(result, event)
+	}
+	return result
+}
+
+// 後から削除したタスクのイベントはタスクの内容が空になっていることがあるので、空行を出力しないようにする
+// skip の場合は取り除き、そうでなければ "(deleted task <タスクの ID>)" に置き換える
+func fillEmptyContent(events []Event, skip bool) []Event {
+	var result []Event
+	for _, event := range events {
+		if strings.TrimSpace(event.Content) == "" {
+			if skip {
+				continue
+			}
+			event.Content = "(deleted task)"
+			if event.TaskID != "" {
+				event.Content = fmt.Sprintf("(deleted task %s)", event.TaskID)
+			}
+		}
+		result = append(result, event)
+	}
+	return result
+}
+
+// --grep の正規表現をコンパイルする。caseSensitive でない場合は大文字小文字を区別しない
+func compileGrepPattern(pattern string, caseSensitive bool) (*regexp.Regexp, error) {
+	if !caseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("grep pattern compile error: %w", err)
+	}
+	return re, nil
+}
+
+// タスクの内容が re にマッチするイベントだけにする。re が nil の場合は絞り込まない
+func filterContent(events []Event, re *regexp.Regexp) []Event {
+	if re == nil {
+		return events
+	}
+	var result []Event
+	for _, event := range events {
+		if re.MatchString(event.Content) {
+			result = append(result, event)
+		}
+	}
+	return result
+}
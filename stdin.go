@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"todoistreport/todoist"
+)
+
+// --project にこの値を指定すると、標準入力からプロジェクト名を読む
+const projectsFromStdin = "-"
+
+// 1行に1つのプロジェクト名を読む。空行と # で始まる行は読み飛ばす
+func readProjectNames(r io.Reader) ([]string, error) {
+	var names []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		names = append(names, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("stdin read error: %w", err)
+	}
+	return names, nil
+}
+
+// 標準入力から読んだプロジェクトごとに、順番にレポートを出力する
+// プロジェクト一覧は --project-cache-ttl のキャッシュに保存されるので、2つ目以降のプロジェクトではキャッシュを使う
+// 見つからないプロジェクトや0件のプロジェクトがあっても残りのプロジェクトは出力し、最後にそのエラーを返す
+func runProjectsFromStdin(ctx context.Context, cfg config, r io.Reader) error {
+	// 同じファイルに書き込むと前のプロジェクトのレポートを上書きしてしまう
+	if cfg.output != "" {
+		return fmt.Errorf("--output is not supported with --project %s", projectsFromStdin)
+	}
+	// 続けて出力しても読めるように、1つのドキュメントにならない形式だけにする
+	if !formatIn(cfg.format, formatText, formatMarkdown, formatChecklist, formatJSONL) {
+		return fmt.Errorf("--project %s is not supported with format %q", projectsFromStdin, cfg.format)
+	}
+
+	names, err := readProjectNames(r)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return errors.New("no project names in stdin")
+	}
+
+	var lastErr error
+	for i, name := range names {
+		if i > 0 && cfg.format != formatJSONL {
+			if _, err := io.WriteString(cfg.stdout, "\n"); err != nil {
+				return fmt.Errorf("write error: %w", err)
+			}
+		}
+		projectCfg := cfg
+		projectCfg.projectName = name
+		err := run(ctx, projectCfg)
+		// 見つからないプロジェクトは run が警告を出している。0件のプロジェクトもレポートは出力している
+		if errors.Is(err, todoist.ErrProjectNotFound) || errors.Is(err, errNoEvents) {
+			lastErr = err
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return lastErr
+}
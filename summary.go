@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type dailyCount struct {
+	Date  time.Time
+	Count int
+}
+
+// 期間内の日ごとの完了数を集計する。完了数が0の日も含める
+// --since-last-run のように since が日の途中の場合も、since の日の0時から until を含む日までを1日ずつ数える
+func countByDay(events []Event, p period) []dailyCount {
+	counts := make(map[string]int)
+	for _, event := range events {
+		counts[event.Date.Format(dateLayout)]++
+	}
+
+	var result []dailyCount
+	start := time.Date(p.since.Year(), p.since.Month(), p.since.Day(), 0, 0, 0, 0, p.since.Location())
+	for day := start; day.Before(p.until); day = day.AddDate(0, 0, 1) {
+		result = append(result, dailyCount{
+			Date:  day,
+			Count: counts[day.Format(dateLayout)],
+		})
+	}
+	return result
+}
+
+// スパークラインに使うブロック文字。低い順
+var sparklineBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// 日ごとの完了数を最大値に合わせてブロック文字の高さにする。全て0の場合は一番低いブロックだけにする
+// unicode が false の場合は、ブロック文字の代わりにカンマ区切りの数字にする
+func sparkline(counts []dailyCount, unicode bool) string {
+	if !unicode {
+		values := make([]string, 0, len(counts))
+		for _, c := range counts {
+			values = append(values, strconv.Itoa(c.Count))
+		}
+		return strings.Join(values, ",")
+	}
+
+	max := 0
+	for _, c := range counts {
+		if c.Count > max {
+			max = c.Count
+		}
+	}
+	var b strings.Builder
+	for _, c := range counts {
+		level := 0
+		if max > 0 {
+			level = c.Count * (len(sparklineBlocks) - 1) / max
+		}
+		b.WriteRune(sparklineBlocks[level])
+	}
+	return b.String()
+}
+
+// 1件以上完了した日が続いた最長の日数と、期間の最後の日（now より後の日は含めない）で終わる現在の連続日数を返す
+// 日の区切りは countByDay と同じく期間のタイムゾーンで判定する
+func streaks(counts []dailyCount, now time.Time) (longest, current int) {
+	for _, c := range counts {
+		if c.Date.After(now) {
+			break
+		}
+		if c.Count == 0 {
+			current = 0
+			continue
+		}
+		current++
+		if current > longest {
+			longest = current
+		}
+	}
+	return longest, current
+}
+
+func renderSummary(w io.Writer, counts []dailyCount, format string, unicode bool, now time.Time) error {
+	total := 0
+	for _, c := range counts {
+		total += c.Count
+	}
+	longest, current := streaks(counts, now)
+
+	var buf bytes.Buffer
+	switch format {
+	case formatMarkdown:
+		buf.WriteString("\n| Date | Count |\n| --- | --- |\n")
+		for _, c := range counts {
+			fmt.Fprintf(&buf, "| %s | %d |\n", c.Date.Format(dateLayout), c.Count)
+		}
+		fmt.Fprintf(&buf, "| Total | %d |\n", total)
+		fmt.Fprintf(&buf, "\nTrend: `%s`\n", sparkline(counts, unicode))
+		fmt.Fprintf(&buf, "\nLongest streak: %s, current streak: %s\n", plural(longest, "day"), plural(current, "day"))
+	default:
+		buf.WriteString("\n")
+		for _, c := range counts {
+			fmt.Fprintf(&buf, "%s %d\n", c.Date.Format(dateLayout), c.Count)
+		}
+		fmt.Fprintf(&buf, "Total %d\n", total)
+		fmt.Fprintf(&buf, "Trend %s\n", sparkline(counts, unicode))
+		fmt.Fprintf(&buf, "Streak longest %s, current %s\n", plural(longest, "day"), plural(current, "day"))
+	}
+
+	if _, err := buf.WriteTo(w); err != nil {
+		return fmt.Errorf("write error: %w", err)
+	}
+	return nil
+}
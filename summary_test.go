@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCountByDaySinceMidDay(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	// --since-last-run のように since が前日の夜の場合も、until の日（今日）まで数える
+	p := period{
+		since: time.Date(2024, 3, 19, 21, 30, 0, 0, loc),
+		until: time.Date(2024, 3, 20, 9, 0, 0, 0, loc),
+	}
+	events := []Event{
+		{ID: "1", Date: time.Date(2024, 3, 19, 22, 0, 0, 0, loc)},
+		{ID: "2", Date: time.Date(2024, 3, 20, 8, 0, 0, 0, loc)},
+		{ID: "3", Date: time.Date(2024, 3, 20, 8, 30, 0, 0, loc)},
+	}
+
+	got := countByDay(events, p)
+	want := []dailyCount{
+		{Date: time.Date(2024, 3, 19, 0, 0, 0, 0, loc), Count: 1},
+		{Date: time.Date(2024, 3, 20, 0, 0, 0, 0, loc), Count: 2},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("countByDay() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if !got[i].Date.Equal(want[i].Date) || got[i].Count != want[i].Count {
+			t.Errorf("countByDay()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
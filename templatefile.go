@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// --template-file で使える関数
+var reportTemplateFuncs = template.FuncMap{
+	// {{formatDate .Date}} でテキスト形式と同じ日時にする
+	"formatDate": func(t time.Time) string { return t.Format(reportDateLayout) },
+	// {{formatTime "2006-01-02" .Date}} のように Go のレイアウトを指定する
+	"formatTime": func(layout string, t time.Time) string { return t.Format(layout) },
+	"join":       strings.Join,
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+}
+
+// テンプレートのエラーに含まれる「名前:行番号」
+var templateErrorLine = regexp.MustCompile(`^template: [^:]+:(\d+)`)
+
+// reportTemplate は --template-file のテンプレート。エラーに行を出すために元のテキストも持つ
+type reportTemplate struct {
+	tmpl   *template.Template
+	source string
+}
+
+// --template-file のテンプレートを読み込む。誤りは API にリクエストする前に知らせる
+func loadReportTemplate(path string) (*reportTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("template file read error: %w", err)
+	}
+	source := string(data)
+	tmpl, err := template.New(filepath.Base(path)).Funcs(reportTemplateFuncs).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("template parse error: %w", withTemplateLine(err, source))
+	}
+	return &reportTemplate{tmpl: tmpl, source: source}, nil
+}
+
+// report（{{.Project}}, {{.Period}}, {{.Total}}, {{range .Events}}{{.Date}} {{.Content}}{{end}}）にテンプレートを適用する
+// 途中で失敗した場合に中途半端な出力にならないように、全て実行してから書き込む
+func renderTemplate(w io.Writer, t *reportTemplate, r report) error {
+	var buf strings.Builder
+	if err := t.tmpl.Execute(&buf, r); err != nil {
+		return fmt.Errorf("template execute error: %w", withTemplateLine(err, t.source))
+	}
+	if _, err := io.WriteString(w, buf.String()); err != nil {
+		return fmt.Errorf("write error: %w", err)
+	}
+	return nil
+}
+
+// エラーの行番号の行をエラーメッセージに付け足して、どこが誤っているか分かるようにする
+func withTemplateLine(err error, source string) error {
+	m := templateErrorLine.FindStringSubmatch(err.Error())
+	if m == nil {
+		return err
+	}
+	n, convErr := strconv.Atoi(m[1])
+	lines := strings.Split(source, "\n")
+	if convErr != nil || n < 1 || n > len(lines) {
+		return err
+	}
+	return fmt.Errorf("%w\n  %d | %s", err, n, lines[n-1])
+}
@@ -0,0 +1,222 @@
+package todoist
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type GetActivityLogResponse struct {
+	Events []ActivityEvent `json:"events"`
+	Count  int             `json:"count"`
+}
+
+// ActivityEvent はアクティビティログの1イベント（API のレスポンスそのままの形）
+type ActivityEvent struct {
+	ID              uint64            `json:"id"`
+	ObjectType      string            `json:"object_type"`
+	ObjectID        string            `json:"object_id"`
+	EventType       string            `json:"event_type"`
+	EventDate       time.Time         `json:"event_date"`
+	ParentProjectID string            `json:"parent_project_id"`
+	ParentItemID    *string           `json:"parent_item_id"`
+	InitiatorID     *string           `json:"initiator_id"`
+	ExtraData       ActivityExtraData `json:"extra_data,omitempty"`
+}
+
+type ActivityExtraData struct {
+	LastDueDate *time.Time `json:"last_due_date"`
+	DueDate     time.Time  `json:"due_date"`
+	Content     string     `json:"content"`
+	Client      string     `json:"client"`
+}
+
+// ToEvents はレスポンスのイベントを Event に変換する
+func (r GetActivityLogResponse) ToEvents() []Event {
+	events := make([]Event, 0, len(r.Events))
+	for _, event := range r.Events {
+		events = append(events, event.Normalize())
+	}
+	return events
+}
+
+// Normalize はアクティビティログのイベントを Event に変換する
+func (e ActivityEvent) Normalize() Event {
+	event := Event{
+		ID:          strconv.FormatUint(e.ID, 10),
+		Date:        e.EventDate,
+		Content:     e.ExtraData.Content,
+		ProjectID:   e.ParentProjectID,
+		EventType:   e.EventType,
+		TaskID:      e.ObjectID,
+		Client:      e.ExtraData.Client,
+		LastDueDate: e.ExtraData.LastDueDate,
+	}
+	if e.ParentItemID != nil {
+		event.ParentTaskID = *e.ParentItemID
+	}
+	if e.InitiatorID != nil {
+		event.InitiatorID = *e.InitiatorID
+	}
+	if !e.ExtraData.DueDate.IsZero() {
+		dueDate := e.ExtraData.DueDate
+		event.DueDate = &dueDate
+	}
+	return event
+}
+
+// EventTypes は Todoist のアクティビティログで指定できるイベント種別
+var EventTypes = []string{
+	"added",
+	"updated",
+	"deleted",
+	"completed",
+	"uncompleted",
+	"archived",
+	"unarchived",
+	"shared",
+	"left",
+}
+
+// ValidateEventType は eventType が Todoist で指定できるイベント種別かどうかを確認する
+func ValidateEventType(eventType string) error {
+	for _, t := range EventTypes {
+		if eventType == t {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown event type %q (available: %s)", eventType, strings.Join(EventTypes, ", "))
+}
+
+// DefaultActivityLogLimit は ActivityLogOptions.Limit を省略した場合の1回あたりの取得件数（Todoist の上限）
+const DefaultActivityLogLimit = 100
+
+// ActivityLogOptions はアクティビティログの取得条件。ゼロ値のままでも今週の完了したタスクを取得できる
+type ActivityLogOptions struct {
+	// ProjectID を省略した場合はアカウント全体のログを取得する
+	ProjectID string
+	// Page は今日を含む週を0として、何週前のログを取得するかを表す
+	Page   int
+	Offset int
+	// Limit を省略した場合は DefaultActivityLogLimit 件ずつ取得する
+	Limit int
+	// EventTypes を省略した場合は完了（completed）したタスクのログを取得する
+	EventTypes []string
+}
+
+func (opts ActivityLogOptions) withDefaults() ActivityLogOptions {
+	if opts.Limit <= 0 {
+		opts.Limit = DefaultActivityLogLimit
+	}
+	return opts
+}
+
+// ActivityLog は opts の条件でアクティビティログを1回分取得する
+func (c *Client) ActivityLog(ctx context.Context, opts ActivityLogOptions) (GetActivityLogResponse, error) {
+	opts = opts.withDefaults()
+	req, err := c.newActivityLogRequest(ctx, opts)
+	if err != nil {
+		return GetActivityLogResponse{}, err
+	}
+
+	c.logf("activity log project_id=%q page=%d offset=%d limit=%d", opts.ProjectID, opts.Page, opts.Offset, opts.Limit)
+	var response GetActivityLogResponse
+	if err := c.do(req, &response); err != nil {
+		return GetActivityLogResponse{}, err
+	}
+	c.logf("activity log project_id=%q page=%d offset=%d count=%d events=%d", opts.ProjectID, opts.Page, opts.Offset, response.Count, len(response.Events))
+
+	return response, nil
+}
+
+// ActivityLogRaw は ActivityLog と同じ条件で取得したレスポンスの JSON をそのまま返す
+// GetActivityLogResponse に含まれないフィールドを確認するなど、デバッグに使う
+func (c *Client) ActivityLogRaw(ctx context.Context, opts ActivityLogOptions) (json.RawMessage, error) {
+	opts = opts.withDefaults()
+	req, err := c.newActivityLogRequest(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	c.logf("activity log (raw) project_id=%q page=%d offset=%d limit=%d", opts.ProjectID, opts.Page, opts.Offset, opts.Limit)
+	var response json.RawMessage
+	if err := c.do(req, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (c *Client) newActivityLogRequest(ctx context.Context, opts ActivityLogOptions) (*http.Request, error) {
+	getURL, err := c.endpoint(activityLogGetPath)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	switch len(opts.EventTypes) {
+	case 0:
+		params.Add("event_type", "completed")
+	case 1:
+		params.Add("event_type", opts.EventTypes[0])
+	default:
+		// 複数のイベント種別は object_event_types に "object_type:event_type" 形式で指定する。object_type は空で全種別になる
+		objectEventTypes := make([]string, 0, len(opts.EventTypes))
+		for _, eventType := range opts.EventTypes {
+			objectEventTypes = append(objectEventTypes, ":"+eventType)
+		}
+		data, err := json.Marshal(objectEventTypes)
+		if err != nil {
+			return nil, fmt.Errorf("object_event_types marshal error: %w", err)
+		}
+		params.Add("object_event_types", string(data))
+	}
+	// ProjectID を指定しない場合はアカウント全体のアクティビティを取得する
+	if opts.ProjectID != "" {
+		params.Add("parent_project_id", opts.ProjectID)
+	}
+	params.Add("page", strconv.Itoa(opts.Page))
+	params.Add("offset", strconv.Itoa(opts.Offset))
+	params.Add("limit", strconv.Itoa(opts.Limit))
+	getURL.RawQuery = params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, getURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request error: %w", err)
+	}
+	return req, nil
+}
+
+// ActivityLogAll は opts.Page のアクティビティログを全件取得する
+// 1週間(1ページ)で Limit 件以上のタスクをこなしている場合は、Count に達するまで offset をずらして取得する
+func (c *Client) ActivityLogAll(ctx context.Context, opts ActivityLogOptions) (GetActivityLogResponse, error) {
+	var result GetActivityLogResponse
+	seen := make(map[uint64]struct{})
+	for {
+		response, err := c.ActivityLog(ctx, opts)
+		if err != nil {
+			return GetActivityLogResponse{}, err
+		}
+		result.Count = response.Count
+
+		// 取得の合間に新しいイベントが追加されると offset がずれて重複することがあるので ID で重複を除く
+		for _, event := range response.Events {
+			if _, ok := seen[event.ID]; ok {
+				continue
+			}
+			seen[event.ID] = struct{}{}
+			result.Events = append(result.Events, event)
+		}
+
+		opts.Offset += len(response.Events)
+		if len(response.Events) == 0 || opts.Offset >= response.Count {
+			break
+		}
+	}
+
+	return result, nil
+}
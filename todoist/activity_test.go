@@ -0,0 +1,64 @@
+package todoist
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// fetch.go のように複数の goroutine から同じ Client で ActivityLog を呼んでもデータ競合しないこと
+// go test -race で実行する
+func TestClientActivityLogParallel(t *testing.T) {
+	const body = `{"events":[{"id":955333384,"object_type":"item","object_id":"2995104339","event_type":"completed","event_date":"2023-01-28T13:14:28Z","parent_project_id":"2203306141","extra_data":{"content":"牛乳"}}],"count":1}`
+
+	var refreshCount int32
+	transport := stubTransport(func(req *http.Request) (*http.Response, error) {
+		res := &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}
+		switch {
+		case req.URL.String() == DefaultOAuthTokenURL:
+			atomic.AddInt32(&refreshCount, 1)
+			res.Body = io.NopCloser(strings.NewReader(`{"access_token":"new-token"}`))
+		case req.Header.Get("Authorization") != "Bearer new-token":
+			// 最初のトークンは期限切れとして、OAuth のトークン更新を並行して走らせる
+			res.StatusCode = http.StatusUnauthorized
+			res.Body = io.NopCloser(strings.NewReader(`{"error":"unauthorized"}`))
+		}
+		return res, nil
+	})
+	client := NewClient("expired-token", WithTransport(transport), WithMaxRetries(0),
+		WithOAuthRefresh(OAuthConfig{ClientID: "id", ClientSecret: "secret", RefreshToken: "refresh"}))
+
+	const parallelism = 8
+	var wg sync.WaitGroup
+	errs := make([]error, parallelism)
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			res, err := client.ActivityLog(context.Background(), ActivityLogOptions{Page: i})
+			if err == nil && len(res.ToEvents()) != 1 {
+				t.Errorf("page %d: len(ToEvents()) = %d, want 1", i, len(res.ToEvents()))
+			}
+			errs[i] = err
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("page %d: ActivityLog() error = %v", i, err)
+		}
+	}
+	if n := atomic.LoadInt32(&refreshCount); n != 1 {
+		t.Errorf("oauth token refresh count = %d, want 1", n)
+	}
+}
@@ -0,0 +1,245 @@
+package todoist
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL は WithBaseURL を指定しない場合の API のベース URL
+const DefaultBaseURL = "https://api.todoist.com"
+
+const (
+	syncGetPath        = "/sync/v9/sync"
+	activityLogGetPath = "/sync/v9/activity/get"
+)
+
+const (
+	DefaultTimeout    = 30 * time.Second
+	DefaultMaxRetries = 3
+	// DefaultUserAgent は WithUserAgent を指定しない場合に送信する User-Agent
+	DefaultUserAgent = "todoistreport (+https://github.com/kyokomi/todoistreport)"
+)
+
+// Client は Todoist Sync API のクライアント
+// Client のフィールドは NewClient で設定した後に変更しないので、1つの Client を複数の goroutine から同時に使ってよい
+// WithOAuthRefresh で更新するアクセストークンだけは変わるが、ロックして読み書きする
+// リクエストごとの状態（ページ・オフセットなど）は引数で渡し、Client には保持しない
+// WithHTTPClient, WithLogger で渡す *http.Client, *log.Logger もそれぞれ複数の goroutine から同時に使える
+type Client struct {
+	apiToken   string
+	httpClient *http.Client
+	maxRetries int
+	logger     *log.Logger
+	userAgent  string
+	baseURL    string
+	// WithOAuthRefresh を指定した場合は、更新したアクセストークンをここで持つ
+	oauth *oauthRefresher
+	// WithRateLimiter を指定しない場合は nil で、待たずに送る
+	limiter RateLimiter
+}
+
+// RateLimiter はリクエストを送る前に呼ばれ、送ってよい時刻まで待つ
+// 複数の Client で同じ RateLimiter を使うと、Client をまたいだ合計のリクエスト数を制限できる
+// 複数の goroutine から同時に呼ばれるので、Wait は並行に呼び出せる必要がある
+type RateLimiter interface {
+	Wait(ctx context.Context) error
+}
+
+// Option は Client の設定を変更する
+type Option func(*Client)
+
+// WithHTTPClient は API リクエストに使う *http.Client を差し替える
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTransport は API リクエストに使う http.RoundTripper を差し替える
+// ネットワークに接続せずにレスポンスを返すスタブを差し込む用途を想定している
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		httpClient := *c.httpClient
+		httpClient.Transport = transport
+		c.httpClient = &httpClient
+	}
+}
+
+// WithMaxRetries は一時的なエラーの際に最大何回までリトライするかを指定する。0でリトライしない
+func WithMaxRetries(n int) Option {
+	return func(c *Client) {
+		c.maxRetries = n
+	}
+}
+
+// WithLogger は送信するリクエストなどのデバッグログの出力先を指定する
+// ログに API トークンは含めない
+func WithLogger(logger *log.Logger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// WithRateLimiter はリトライや OAuth のトークン更新も含めて、全てのリクエストを送る前に limiter で待つ
+// 待っている時間は *http.Client の Timeout に含まれない
+func WithRateLimiter(limiter RateLimiter) Option {
+	return func(c *Client) {
+		c.limiter = limiter
+	}
+}
+
+// WithUserAgent は全てのリクエストで送信する User-Agent を指定する
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithBaseURL は API のベース URL（スキームとホスト）を差し替える
+// モックサーバーや別バージョンの API に向けて試す用途を想定している
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// NewClient は apiToken で認証する Client を返す
+func NewClient(apiToken string, opts ...Option) *Client {
+	c := &Client{
+		apiToken:   apiToken,
+		httpClient: &http.Client{Timeout: DefaultTimeout},
+		maxRetries: DefaultMaxRetries,
+		userAgent:  DefaultUserAgent,
+		baseURL:    DefaultBaseURL,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *Client) do(req *http.Request, v interface{}) error {
+	if c.apiToken == "" {
+		return ErrNoAPIToken
+	}
+	token := c.token()
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("User-Agent", c.userAgent)
+
+	c.logf("request %s %s", req.Method, c.redact(req.URL.String()))
+	res, err := c.doWithRetry(req)
+	if err != nil {
+		return fmt.Errorf("http request do error: %w", err)
+	}
+	// OAuth のアクセストークンの期限が切れていれば、更新して1回だけリトライする
+	if res.StatusCode == http.StatusUnauthorized && c.oauth != nil {
+		res.Body.Close()
+		res, err = c.retryWithRefreshedToken(req, token)
+		if err != nil {
+			return err
+		}
+	}
+	defer res.Body.Close()
+	c.logf("response %s %s status=%d", req.Method, c.redact(req.URL.String()), res.StatusCode)
+
+	// エラー時のボディは期待する JSON の形ではないので、Unmarshal する前にステータスコードを確認する
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorBodySize+1))
+		return newResponseError(res, body)
+	}
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("http get response read error: %w", err)
+	}
+
+	// 未知のフィールドは無視して読む。Todoist 側でフィールドが追加されても動き続けるようにする
+	// どのエンドポイントのどのページで失敗したか分かるように、ページ・オフセットを含むクエリもエラーに含める
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("http get response json unmarshall error: %s %s status=%d: %w", req.Method, c.redact(req.URL.RequestURI()), res.StatusCode, err)
+	}
+	c.checkUnknownFields(req, data, v)
+
+	return nil
+}
+
+// レスポンスに型で定義していないフィールドがあればデバッグログに出して、スキーマの変化に気付けるようにする
+// encoding/json は最初に見つかった未知のフィールドしか返さないので、ログに出すのも1つだけ
+func (c *Client) checkUnknownFields(req *http.Request, data []byte, v interface{}) {
+	if c.logger == nil {
+		return
+	}
+	// v と同じ型の新しい値に厳密に decode し直す。v の中身は書き換えない
+	strict := reflect.New(reflect.TypeOf(v).Elem()).Interface()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(strict); err != nil && strings.HasPrefix(err.Error(), "json: unknown field ") {
+		c.logf("warning: response %s %s has %s", req.Method, c.redact(req.URL.Path), strings.TrimPrefix(err.Error(), "json: "))
+	}
+}
+
+// リクエストに使うトークン。WithOAuthRefresh で更新した場合は更新後のトークンを返す
+func (c *Client) token() string {
+	if c.oauth != nil {
+		return c.oauth.token()
+	}
+	return c.apiToken
+}
+
+func (c *Client) retryWithRefreshedToken(req *http.Request, usedToken string) (*http.Response, error) {
+	c.logf("refresh oauth access token after %s %s status=%d", req.Method, c.redact(req.URL.String()), http.StatusUnauthorized)
+	token, err := c.oauth.refresh(req.Context(), c, usedToken)
+	if err != nil {
+		return nil, err
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("request body rewind error: %w", err)
+		}
+		req.Body = body
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	res, err := c.doWithRetry(req)
+	if err != nil {
+		return nil, fmt.Errorf("http request do error: %w", err)
+	}
+	return res, nil
+}
+
+// ベース URL に path をつなげた URL を返す
+func (c *Client) endpoint(path string) (*url.URL, error) {
+	u, err := url.Parse(c.baseURL + path)
+	if err != nil {
+		return nil, fmt.Errorf("url parse error: %w", err)
+	}
+	return u, nil
+}
+
+func (c *Client) logf(format string, v ...interface{}) {
+	if c.logger == nil {
+		return
+	}
+	c.logger.Printf(format, v...)
+}
+
+// 万が一 URL などにトークンが含まれていてもログに出さない
+func (c *Client) redact(s string) string {
+	if c.apiToken == "" {
+		return s
+	}
+	s = strings.ReplaceAll(s, c.apiToken, "[REDACTED]")
+	if token := c.token(); token != "" {
+		s = strings.ReplaceAll(s, token, "[REDACTED]")
+	}
+	return s
+}
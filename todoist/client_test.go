@@ -0,0 +1,167 @@
+package todoist
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubTransport はネットワークに接続せずに、リクエストごとに固定のレスポンスを返す
+type stubTransport func(req *http.Request) (*http.Response, error)
+
+func (f stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// body を 200 で返すスタブの http.RoundTripper
+func jsonResponse(body string) stubTransport {
+	return func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	}
+}
+
+func newStubClient(transport http.RoundTripper) *Client {
+	return NewClient("test-token", WithTransport(transport), WithMaxRetries(0))
+}
+
+func TestClientProjects(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []Project
+	}{
+		{
+			name: "full sync",
+			body: `{"projects":[{"id":"1","name":"Inbox","inbox_project":true,"child_order":0},{"id":"2","name":"Work","shared":true,"child_order":1}],"full_sync":true,"sync_token":"token1"}`,
+			want: []Project{
+				{ID: "1", Name: "Inbox", InboxProject: true},
+				{ID: "2", Name: "Work", Shared: true, ChildOrder: 1},
+			},
+		},
+		{
+			name: "deleted projects are removed",
+			body: `{"projects":[{"id":"1","name":"Inbox"},{"id":"3","name":"Old","is_deleted":true}],"full_sync":true,"sync_token":"token1"}`,
+			want: []Project{
+				{ID: "1", Name: "Inbox"},
+			},
+		},
+		{
+			name: "no projects",
+			body: `{"projects":[],"full_sync":true,"sync_token":"token1"}`,
+			want: []Project{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newStubClient(jsonResponse(tt.body))
+			got, err := client.Projects(context.Background())
+			if err != nil {
+				t.Fatalf("Projects() error = %v", err)
+			}
+			if len(got.Projects) != len(tt.want) || (len(tt.want) > 0 && !reflect.DeepEqual(got.Projects, tt.want)) {
+				t.Errorf("Projects() = %+v, want %+v", got.Projects, tt.want)
+			}
+			if got.SyncToken != "token1" {
+				t.Errorf("SyncToken = %q, want %q", got.SyncToken, "token1")
+			}
+		})
+	}
+}
+
+func TestClientActivityLog(t *testing.T) {
+	parentItemID := "2995104339"
+	initiatorID := "1855589"
+	lastDueDate := time.Date(2023, 1, 27, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		body string
+		want []Event
+	}{
+		{
+			name: "completed event with extra_data",
+			body: `{"events":[{"id":955333384,"object_type":"item","object_id":"2995104339","event_type":"completed","event_date":"2023-01-28T13:14:28Z","parent_project_id":"2203306141","parent_item_id":null,"initiator_id":null,"extra_data":{"content":"牛乳","client":"Todoist-iOS"}}],"count":1}`,
+			want: []Event{{
+				ID:        "955333384",
+				Date:      time.Date(2023, 1, 28, 13, 14, 28, 0, time.UTC),
+				Content:   "牛乳",
+				ProjectID: "2203306141",
+				EventType: "completed",
+				TaskID:    "2995104339",
+				Client:    "Todoist-iOS",
+			}},
+		},
+		{
+			name: "subtask with initiator and due dates",
+			body: `{"events":[{"id":955333385,"object_type":"item","object_id":"2995104340","event_type":"completed","event_date":"2023-01-29T01:02:03Z","parent_project_id":"2203306141","parent_item_id":"2995104339","initiator_id":"1855589","extra_data":{"content":"果物","last_due_date":"2023-01-27T00:00:00Z","due_date":"2023-01-30T00:00:00Z"}}],"count":1}`,
+			want: []Event{{
+				ID:           "955333385",
+				Date:         time.Date(2023, 1, 29, 1, 2, 3, 0, time.UTC),
+				Content:      "果物",
+				ProjectID:    "2203306141",
+				EventType:    "completed",
+				TaskID:       "2995104340",
+				ParentTaskID: parentItemID,
+				InitiatorID:  initiatorID,
+				LastDueDate:  &lastDueDate,
+				DueDate:      timePtr(time.Date(2023, 1, 30, 0, 0, 0, 0, time.UTC)),
+			}},
+		},
+		{
+			name: "event without extra_data",
+			body: `{"events":[{"id":955333386,"object_type":"item","object_id":"2995104341","event_type":"deleted","event_date":"2023-01-30T00:00:00Z","parent_project_id":"2203306141"}],"count":1}`,
+			want: []Event{{
+				ID:        "955333386",
+				Date:      time.Date(2023, 1, 30, 0, 0, 0, 0, time.UTC),
+				ProjectID: "2203306141",
+				EventType: "deleted",
+				TaskID:    "2995104341",
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newStubClient(jsonResponse(tt.body))
+			got, err := client.ActivityLog(context.Background(), ActivityLogOptions{})
+			if err != nil {
+				t.Fatalf("ActivityLog() error = %v", err)
+			}
+			if got.Count != 1 {
+				t.Errorf("Count = %d, want 1", got.Count)
+			}
+			if events := got.ToEvents(); !reflect.DeepEqual(events, tt.want) {
+				t.Errorf("ToEvents() = %+v, want %+v", events, tt.want)
+			}
+		})
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+// sleepLimiter は毎回 d だけ待つ RateLimiter
+type sleepLimiter time.Duration
+
+func (l sleepLimiter) Wait(ctx context.Context) error {
+	time.Sleep(time.Duration(l))
+	return nil
+}
+
+// RateLimiter で待つ時間は http.Client の Timeout に含まれないこと
+func TestClientRateLimiterOutsideTimeout(t *testing.T) {
+	httpClient := &http.Client{Transport: jsonResponse(`{"projects":[],"full_sync":true,"sync_token":"token1"}`), Timeout: 50 * time.Millisecond}
+	client := NewClient("test-token", WithHTTPClient(httpClient), WithMaxRetries(0), WithRateLimiter(sleepLimiter(100*time.Millisecond)))
+	if _, err := client.Projects(context.Background()); err != nil {
+		t.Fatalf("Projects() error = %v", err)
+	}
+}
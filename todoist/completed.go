@@ -0,0 +1,119 @@
+package todoist
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const completedGetPath = "/api/v1/tasks/completed/by_completion_date"
+
+// DefaultCompletedItemsLimit は CompletedItemsOptions.Limit を省略した場合の1回あたりの取得件数
+const DefaultCompletedItemsLimit = 200
+
+// CompletedItem は完了済みタスク API が返す完了したタスク
+type CompletedItem struct {
+	ID          string    `json:"id"`
+	Content     string    `json:"content"`
+	ProjectID   string    `json:"project_id"`
+	ParentID    *string   `json:"parent_id"`
+	CompletedAt time.Time `json:"completed_at"`
+	CompletedBy *string   `json:"completed_by_uid"`
+	Labels      []string  `json:"labels"`
+}
+
+// Normalize は完了済みタスクを Event に変換する
+// 完了済みタスク API にはイベント ID が無いので、タスク ID と完了日時から一意な ID を作る
+func (i CompletedItem) Normalize() Event {
+	event := Event{
+		ID:        i.ID + "-" + strconv.FormatInt(i.CompletedAt.Unix(), 10),
+		Date:      i.CompletedAt,
+		Content:   i.Content,
+		ProjectID: i.ProjectID,
+		EventType: "completed",
+		TaskID:    i.ID,
+	}
+	if i.ParentID != nil {
+		event.ParentTaskID = *i.ParentID
+	}
+	if i.CompletedBy != nil {
+		event.InitiatorID = *i.CompletedBy
+	}
+	return event
+}
+
+type GetCompletedItemsResponse struct {
+	Items      []CompletedItem `json:"items"`
+	NextCursor *string         `json:"next_cursor"`
+}
+
+// CompletedItemsOptions は完了済みタスクの取得条件
+// Todoist の制限で Since から Until までは最大3ヶ月まで
+type CompletedItemsOptions struct {
+	// ProjectID を省略した場合はアカウント全体のタスクを取得する
+	ProjectID string
+	Since     time.Time
+	Until     time.Time
+	// Cursor には前回のレスポンスの NextCursor を指定する
+	Cursor string
+	// Limit を省略した場合は DefaultCompletedItemsLimit 件ずつ取得する
+	Limit int
+}
+
+// CompletedItems は opts の条件で完了済みタスクを1回分取得する
+func (c *Client) CompletedItems(ctx context.Context, opts CompletedItemsOptions) (GetCompletedItemsResponse, error) {
+	getURL, err := c.endpoint(completedGetPath)
+	if err != nil {
+		return GetCompletedItemsResponse{}, err
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultCompletedItemsLimit
+	}
+
+	params := url.Values{}
+	params.Add("since", opts.Since.UTC().Format(time.RFC3339))
+	params.Add("until", opts.Until.UTC().Format(time.RFC3339))
+	params.Add("limit", strconv.Itoa(limit))
+	if opts.ProjectID != "" {
+		params.Add("project_id", opts.ProjectID)
+	}
+	if opts.Cursor != "" {
+		params.Add("cursor", opts.Cursor)
+	}
+	getURL.RawQuery = params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, getURL.String(), nil)
+	if err != nil {
+		return GetCompletedItemsResponse{}, fmt.Errorf("new request error: %w", err)
+	}
+
+	var response GetCompletedItemsResponse
+	if err := c.do(req, &response); err != nil {
+		return GetCompletedItemsResponse{}, err
+	}
+	c.logf("completed items project_id=%q items=%d", opts.ProjectID, len(response.Items))
+
+	return response, nil
+}
+
+// CompletedItemsAll は NextCursor をたどって opts の条件の完了済みタスクを全件取得する
+func (c *Client) CompletedItemsAll(ctx context.Context, opts CompletedItemsOptions) ([]CompletedItem, error) {
+	var items []CompletedItem
+	for {
+		response, err := c.CompletedItems(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, response.Items...)
+
+		if response.NextCursor == nil || *response.NextCursor == "" || *response.NextCursor == opts.Cursor {
+			return items, nil
+		}
+		opts.Cursor = *response.NextCursor
+	}
+}
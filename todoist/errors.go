@@ -0,0 +1,65 @@
+package todoist
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// エラーメッセージに含めるレスポンスボディの最大バイト数
+const maxErrorBodySize = 512
+
+var (
+	// ErrNoAPIToken は API トークンが指定されていない場合のエラー
+	ErrNoAPIToken = errors.New("no API token provided")
+	// ErrInvalidAPIToken は API トークンが不正で認証に失敗した（401, 403）場合のエラー
+	// *APIError は errors.Is でこのエラーと比較できる
+	ErrInvalidAPIToken = errors.New("invalid API token")
+)
+
+// APIError は Todoist API が 2xx 以外のステータスコードを返した場合のエラー
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	if e.isAuthError() {
+		return fmt.Sprintf("%s: status=%d", ErrInvalidAPIToken, e.StatusCode)
+	}
+	return fmt.Sprintf("todoist api error: status=%d body=%q", e.StatusCode, e.Body)
+}
+
+func (e *APIError) Is(target error) bool {
+	return target == ErrInvalidAPIToken && e.isAuthError()
+}
+
+func (e *APIError) isAuthError() bool {
+	return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+}
+
+// RateLimitError は Todoist API が 429 Too Many Requests を返した場合のエラー
+// RetryAfter は Retry-After ヘッダーが無い場合は0になる
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("todoist api rate limited: retry after %s", e.RetryAfter)
+	}
+	return "todoist api rate limited"
+}
+
+func newResponseError(res *http.Response, body []byte) error {
+	if res.StatusCode == http.StatusTooManyRequests {
+		retryAfter, _ := parseRetryAfter(res.Header.Get("Retry-After"))
+		return &RateLimitError{RetryAfter: retryAfter}
+	}
+
+	if len(body) > maxErrorBodySize {
+		body = append(body[:maxErrorBodySize:maxErrorBodySize], "..."...)
+	}
+	return &APIError{StatusCode: res.StatusCode, Body: string(body)}
+}
@@ -0,0 +1,21 @@
+package todoist
+
+import (
+	"time"
+)
+
+// Event は取得元（アクティビティログ、完了済みタスク API）によらず同じ形にしたイベント
+// API のレスポンスの形に依存せずに扱いたい場合に使う
+type Event struct {
+	ID           string
+	Date         time.Time
+	Content      string
+	ProjectID    string
+	EventType    string
+	TaskID       string
+	ParentTaskID string
+	InitiatorID  string
+	Client       string
+	DueDate      *time.Time
+	LastDueDate  *time.Time
+}
@@ -0,0 +1,31 @@
+package todoist
+
+import (
+	"context"
+)
+
+// Item は Sync API の items リソース（未完了のタスク）
+type Item struct {
+	ID        string   `json:"id"`
+	Content   string   `json:"content"`
+	ProjectID string   `json:"project_id"`
+	ParentID  *string  `json:"parent_id"`
+	Labels    []string `json:"labels"`
+	Checked   bool     `json:"checked"`
+	IsDeleted bool     `json:"is_deleted"`
+}
+
+type GetItemsResponse struct {
+	Items     []Item `json:"items"`
+	FullSync  bool   `json:"full_sync"`
+	SyncToken string `json:"sync_token"`
+}
+
+// Items はアカウントの未完了のタスクを取得する
+func (c *Client) Items(ctx context.Context) (GetItemsResponse, error) {
+	var response GetItemsResponse
+	if err := c.sync(ctx, "*", []string{"items"}, &response); err != nil {
+		return GetItemsResponse{}, err
+	}
+	return response, nil
+}
@@ -0,0 +1,30 @@
+package todoist
+
+import (
+	"context"
+)
+
+// Label は Sync API の labels リソース（パーソナルラベル）
+type Label struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Color      string `json:"color"`
+	ItemOrder  int    `json:"item_order"`
+	IsDeleted  bool   `json:"is_deleted"`
+	IsFavorite bool   `json:"is_favorite"`
+}
+
+type GetLabelsResponse struct {
+	Labels    []Label `json:"labels"`
+	FullSync  bool    `json:"full_sync"`
+	SyncToken string  `json:"sync_token"`
+}
+
+// Labels はアカウントのパーソナルラベルを取得する
+func (c *Client) Labels(ctx context.Context) (GetLabelsResponse, error) {
+	var response GetLabelsResponse
+	if err := c.sync(ctx, "*", []string{"labels"}, &response); err != nil {
+		return GetLabelsResponse{}, err
+	}
+	return response, nil
+}
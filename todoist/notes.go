@@ -0,0 +1,31 @@
+package todoist
+
+import (
+	"context"
+	"time"
+)
+
+// Note は Sync API の notes リソース（タスクのコメント）
+type Note struct {
+	ID        string    `json:"id"`
+	ItemID    string    `json:"item_id"`
+	Content   string    `json:"content"`
+	PostedAt  time.Time `json:"posted_at"`
+	IsDeleted bool      `json:"is_deleted"`
+}
+
+type GetNotesResponse struct {
+	Notes     []Note `json:"notes"`
+	FullSync  bool   `json:"full_sync"`
+	SyncToken string `json:"sync_token"`
+}
+
+// Notes はアカウントの未完了のタスクのコメントを取得する
+// 完了したタスクのコメントは Sync API の notes には含まれない
+func (c *Client) Notes(ctx context.Context) (GetNotesResponse, error) {
+	var response GetNotesResponse
+	if err := c.sync(ctx, "*", []string{"notes"}, &response); err != nil {
+		return GetNotesResponse{}, err
+	}
+	return response, nil
+}
@@ -0,0 +1,104 @@
+package todoist
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// DefaultOAuthTokenURL は OAuthConfig.TokenURL を省略した場合にアクセストークンを更新するエンドポイント
+const DefaultOAuthTokenURL = "https://todoist.com/oauth/access_token"
+
+// OAuthConfig は期限切れの OAuth アクセストークンを更新するための設定
+type OAuthConfig struct {
+	ClientID     string
+	ClientSecret string
+	RefreshToken string
+	// TokenURL を省略した場合は DefaultOAuthTokenURL を使う
+	TokenURL string
+}
+
+// WithOAuthRefresh は、NewClient に渡したトークンを OAuth のアクセストークンとして扱い、
+// 401 が返ってきた場合に config のリフレッシュトークンで更新して1回だけリトライする
+// 更新したトークンはこの Client の中だけで使い、保存はしない
+func WithOAuthRefresh(config OAuthConfig) Option {
+	return func(c *Client) {
+		if config.TokenURL == "" {
+			config.TokenURL = DefaultOAuthTokenURL
+		}
+		c.oauth = &oauthRefresher{config: config, accessToken: c.apiToken}
+	}
+}
+
+// oauthRefresher は更新したアクセストークンを持つ。複数の goroutine から同時に使うので mu で守る
+type oauthRefresher struct {
+	mu          sync.Mutex
+	config      OAuthConfig
+	accessToken string
+}
+
+func (o *oauthRefresher) token() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.accessToken
+}
+
+// usedToken で 401 になった場合にアクセストークンを更新する
+// 他の goroutine が先に更新していれば、もう一度更新せずにそのトークンを返す
+func (o *oauthRefresher) refresh(ctx context.Context, c *Client, usedToken string) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.accessToken != usedToken {
+		return o.accessToken, nil
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", o.config.RefreshToken)
+	form.Set("client_id", o.config.ClientID)
+	form.Set("client_secret", o.config.ClientSecret)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("new request error: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	if err := c.waitRateLimit(ctx); err != nil {
+		return "", err
+	}
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("oauth token refresh error: %w", err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxErrorBodySize+1))
+	if err != nil {
+		return "", fmt.Errorf("oauth token refresh read error: %w", err)
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		// リフレッシュトークンも無効な場合は、トークンの設定を見直す必要があるので認証エラーにする
+		return "", fmt.Errorf("oauth token refresh error: status=%d: %w", res.StatusCode, ErrInvalidAPIToken)
+	}
+
+	var response struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("oauth token refresh json unmarshall error: %w", err)
+	}
+	if response.AccessToken == "" {
+		return "", fmt.Errorf("oauth token refresh error: no access_token in response: %w", ErrInvalidAPIToken)
+	}
+	o.accessToken = response.AccessToken
+	// リフレッシュトークンをローテーションするサーバーでは、次の更新に新しいリフレッシュトークンを使う
+	if response.RefreshToken != "" {
+		o.config.RefreshToken = response.RefreshToken
+	}
+	return o.accessToken, nil
+}
@@ -0,0 +1,247 @@
+package todoist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrProjectNotFound は指定した名前のプロジェクトが存在しない場合のエラー
+var ErrProjectNotFound = errors.New("project not exists")
+
+type Project struct {
+	IsArchived   bool   `json:"is_archived"`
+	Color        string `json:"color"`
+	Shared       bool   `json:"shared"`
+	InboxProject bool   `json:"inbox_project"`
+	ID           string `json:"id"`
+	Collapsed    bool   `json:"collapsed"`
+	ChildOrder   int    `json:"child_order"`
+	Name         string `json:"name"`
+	IsDeleted    bool   `json:"is_deleted"`
+	// ParentID は親プロジェクトの ID。トップレベルのプロジェクトは null なので nil になる
+	ParentID  *string `json:"parent_id"`
+	ViewStyle string  `json:"view_style"`
+}
+
+type GetProjectsResponse struct {
+	Projects      []Project `json:"projects"`
+	FullSync      bool      `json:"full_sync"`
+	TempIDMapping struct {
+	} `json:"temp_id_mapping"`
+	SyncToken string `json:"sync_token"`
+}
+
+// FindProjectByName は名前が一致するプロジェクトを返す
+func (r GetProjectsResponse) FindProjectByName(projectName string) (Project, bool) {
+	for _, project := range r.Projects {
+		if projectName == project.Name {
+			return project, true
+		}
+	}
+	return Project{}, false
+}
+
+// MatchOptions はプロジェクトの検索方法
+type MatchOptions struct {
+	// Substring が true の場合、名前の一部が一致するプロジェクトも対象にする
+	Substring bool
+	// IncludeArchived が true の場合、アーカイブ済み（IsArchived）のプロジェクトも対象にする
+	// 削除済み（IsDeleted）のプロジェクトはこの設定に関わらず対象にしない
+	IncludeArchived bool
+}
+
+// MatchProject は query に一致するプロジェクトを返す
+// query がすべて数字でその ID のプロジェクトがあればそれを返し、なければ名前を大文字小文字を区別せずに比較する
+// 複数のプロジェクトが一致した場合は *AmbiguousProjectError、一つも一致しない場合は ErrProjectNotFound を返す
+func (r GetProjectsResponse) MatchProject(query string, opts MatchOptions) (Project, error) {
+	var searchable []Project
+	for _, project := range r.Projects {
+		if project.IsDeleted || (project.IsArchived && !opts.IncludeArchived) {
+			continue
+		}
+		searchable = append(searchable, project)
+	}
+
+	if isDigits(query) {
+		for _, project := range searchable {
+			if project.ID == query {
+				return project, nil
+			}
+		}
+	}
+
+	// 完全一致するものがあれば、大文字小文字だけが違うプロジェクトがあっても優先する
+	for _, project := range searchable {
+		if project.Name == query {
+			return project, nil
+		}
+	}
+
+	var candidates []Project
+	for _, project := range searchable {
+		if strings.EqualFold(project.Name, query) {
+			candidates = append(candidates, project)
+		}
+	}
+	if len(candidates) == 0 && opts.Substring {
+		lowerQuery := strings.ToLower(query)
+		for _, project := range searchable {
+			if strings.Contains(strings.ToLower(project.Name), lowerQuery) {
+				candidates = append(candidates, project)
+			}
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return Project{}, ErrProjectNotFound
+	case 1:
+		return candidates[0], nil
+	default:
+		return Project{}, &AmbiguousProjectError{Query: query, Candidates: candidates}
+	}
+}
+
+// Children は親プロジェクトの ID ごとの子プロジェクトを ChildOrder の順に並べて返す
+// 削除済みのプロジェクトは含めず、アーカイブ済みのプロジェクトは opts.IncludeArchived の場合のみ含める
+func (r GetProjectsResponse) Children(opts MatchOptions) map[string][]Project {
+	children := make(map[string][]Project)
+	for _, project := range r.Projects {
+		if project.ParentID == nil || project.IsDeleted || (project.IsArchived && !opts.IncludeArchived) {
+			continue
+		}
+		children[*project.ParentID] = append(children[*project.ParentID], project)
+	}
+	for _, projects := range children {
+		sort.SliceStable(projects, func(i, j int) bool {
+			return projects[i].ChildOrder < projects[j].ChildOrder
+		})
+	}
+	return children
+}
+
+// Descendants は projectID の子孫のプロジェクトを返す
+// 子プロジェクトは ChildOrder の順に並べ、孫プロジェクトはその親の直後に並べる（深さ優先）
+// 親子関係が循環していても同じプロジェクトを二度返さない
+func (r GetProjectsResponse) Descendants(projectID string, opts MatchOptions) []Project {
+	children := r.Children(opts)
+	visited := map[string]bool{projectID: true}
+	var result []Project
+	var walk func(id string)
+	walk = func(id string) {
+		for _, child := range children[id] {
+			if visited[child.ID] {
+				continue
+			}
+			visited[child.ID] = true
+			result = append(result, child)
+			walk(child.ID)
+		}
+	}
+	walk(projectID)
+	return result
+}
+
+// AmbiguousProjectError は検索条件に複数のプロジェクトが一致した場合のエラー
+type AmbiguousProjectError struct {
+	Query      string
+	Candidates []Project
+}
+
+func (e *AmbiguousProjectError) Error() string {
+	names := make([]string, 0, len(e.Candidates))
+	for _, project := range e.Candidates {
+		names = append(names, fmt.Sprintf("%s (id=%s)", project.Name, project.ID))
+	}
+	return fmt.Sprintf("project %q matches multiple projects: %s", e.Query, strings.Join(names, ", "))
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// SearchProjectByName は projectName に一致するプロジェクトを返す。一致の判定は MatchProject と同じ
+func (c *Client) SearchProjectByName(ctx context.Context, projectName string) (Project, error) {
+	response, err := c.Projects(ctx)
+	if err != nil {
+		return Project{}, fmt.Errorf("get project error: %w", err)
+	}
+
+	return response.MatchProject(projectName, MatchOptions{})
+}
+
+// sync_token をたどってもプロジェクトが返ってき続ける場合に無限ループしないための上限
+const maxProjectSyncRounds = 10
+
+// Projects はアカウントの全プロジェクトを取得する
+// 一度のレスポンスで全件が返ってこない（full_sync でない）場合は、返ってきた sync_token で
+// プロジェクトが返ってこなくなるまで取得を続けてマージする。削除済みのプロジェクトは結果に含めない
+func (c *Client) Projects(ctx context.Context) (GetProjectsResponse, error) {
+	return c.UpdateProjects(ctx, GetProjectsResponse{})
+}
+
+// UpdateProjects は以前に取得した base の SyncToken から差分同期して、追加・変更・削除されたプロジェクトを反映した結果を返す
+// base の SyncToken が空の場合は Projects と同じく全件を取得する
+func (c *Client) UpdateProjects(ctx context.Context, base GetProjectsResponse) (GetProjectsResponse, error) {
+	result := GetProjectsResponse{FullSync: base.FullSync, SyncToken: base.SyncToken}
+	result.Projects = append(result.Projects, base.Projects...)
+	index := make(map[string]int)
+	for i, project := range result.Projects {
+		index[project.ID] = i
+	}
+	syncToken := base.SyncToken
+	if syncToken == "" {
+		syncToken = "*"
+	}
+	for round := 0; round < maxProjectSyncRounds; round++ {
+		var response GetProjectsResponse
+		if err := c.sync(ctx, syncToken, []string{"projects"}, &response); err != nil {
+			return GetProjectsResponse{}, err
+		}
+
+		// 差分同期を要求しても全件が返ってきた場合は、以前の結果を捨てる
+		if response.FullSync {
+			result.Projects = nil
+			index = make(map[string]int)
+		}
+		for _, project := range response.Projects {
+			i, ok := index[project.ID]
+			if !ok {
+				index[project.ID] = len(result.Projects)
+				result.Projects = append(result.Projects, project)
+				continue
+			}
+			// 後から返ってきたものが新しい状態なので上書きする
+			result.Projects[i] = project
+		}
+		result.FullSync = result.FullSync || response.FullSync
+		result.SyncToken = response.SyncToken
+
+		if response.FullSync || len(response.Projects) == 0 || response.SyncToken == "" || response.SyncToken == syncToken {
+			break
+		}
+		syncToken = response.SyncToken
+	}
+
+	projects := result.Projects[:0]
+	for _, project := range result.Projects {
+		if project.IsDeleted {
+			continue
+		}
+		projects = append(projects, project)
+	}
+	result.Projects = projects
+
+	return result, nil
+}
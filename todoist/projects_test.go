@@ -0,0 +1,38 @@
+package todoist
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestProjectParentID(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want *string
+	}{
+		{name: "top level project", body: `{"id":"1","name":"Work","parent_id":null}`, want: nil},
+		{name: "sub project", body: `{"id":"2","name":"Meeting","parent_id":"1"}`, want: stringPtr("1")},
+		{name: "parent_id omitted", body: `{"id":"3","name":"Home"}`, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var project Project
+			if err := json.Unmarshal([]byte(tt.body), &project); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			switch {
+			case tt.want == nil && project.ParentID != nil:
+				t.Errorf("ParentID = %q, want nil", *project.ParentID)
+			case tt.want != nil && project.ParentID == nil:
+				t.Errorf("ParentID = nil, want %q", *tt.want)
+			case tt.want != nil && *project.ParentID != *tt.want:
+				t.Errorf("ParentID = %q, want %q", *project.ParentID, *tt.want)
+			}
+		})
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
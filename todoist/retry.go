@@ -0,0 +1,113 @@
+package todoist
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const retryBaseDelay = 1 * time.Second
+
+// doWithRetry は 429, 502, 503 とネットワークエラー、2xx で空のボディが返ってきた場合に指数バックオフでリトライする
+func (c *Client) doWithRetry(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			// 前回のリクエストで Body を読み切っているので作り直す
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("request body rewind error: %w", err)
+			}
+			req.Body = body
+		}
+
+		// http.Client の Timeout に待ち時間が含まれないように、Do の前に待つ
+		if err := c.waitRateLimit(ctx); err != nil {
+			return nil, err
+		}
+		res, err := c.httpClient.Do(req)
+		if err == nil && res.StatusCode >= 200 && res.StatusCode < 300 {
+			// 接続が不安定な場合は 200 でも空のボディや途中で切れたボディが返ってくるので、読み切ってからリトライするか決める
+			if err = bufferBody(res); err != nil {
+				res = nil
+			}
+		}
+		if err != nil {
+			// context のキャンセルやタイムアウトはリトライしても意味がない
+			if ctx.Err() != nil || attempt >= c.maxRetries {
+				return nil, err
+			}
+		} else if !isRetryableStatus(res.StatusCode) || attempt >= c.maxRetries {
+			return res, nil
+		}
+
+		delay := retryBaseDelay << attempt
+		if res != nil {
+			if d, ok := parseRetryAfter(res.Header.Get("Retry-After")); ok && res.StatusCode == http.StatusTooManyRequests {
+				delay = d
+			}
+			res.Body.Close()
+		}
+
+		c.logf("retry %s %s attempt=%d delay=%s", req.Method, c.redact(req.URL.String()), attempt+1, delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// WithRateLimiter を指定した場合は、リクエストを送ってよい時刻まで待つ
+func (c *Client) waitRateLimit(ctx context.Context) error {
+	if c.limiter == nil {
+		return nil
+	}
+	return c.limiter.Wait(ctx)
+}
+
+// 2xx のレスポンスのボディを読み切って、読み直せるように差し替える。空のボディはエラーにする
+func bufferBody(res *http.Response) error {
+	data, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return fmt.Errorf("http get response read error (status=%d): %w", res.StatusCode, err)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return fmt.Errorf("empty response body (status=%d)", res.StatusCode)
+	}
+	res.Body = io.NopCloser(bytes.NewReader(data))
+	return nil
+}
+
+func isRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable:
+		return true
+	}
+	return false
+}
+
+// Retry-After は秒数か HTTP-date のどちらかで返ってくる
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
@@ -0,0 +1,36 @@
+package todoist
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// sync は Sync API で resourceTypes のリソースを取得して v に Unmarshal する
+// syncToken に "*" を指定すると全件、前回のレスポンスの sync_token を指定すると差分を取得する
+func (c *Client) sync(ctx context.Context, syncToken string, resourceTypes []string, v interface{}) error {
+	getURL, err := c.endpoint(syncGetPath)
+	if err != nil {
+		return err
+	}
+
+	payload := map[string]interface{}{
+		"sync_token":     syncToken,
+		"resource_types": resourceTypes,
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(&payload); err != nil {
+		return fmt.Errorf("payload marshal error: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, getURL.String(), &buf)
+	if err != nil {
+		return fmt.Errorf("new request error: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return c.do(req, v)
+}
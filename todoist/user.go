@@ -0,0 +1,26 @@
+package todoist
+
+import (
+	"context"
+)
+
+// User は Sync API の user リソース（トークンのアカウント自身）
+type User struct {
+	ID       string `json:"id"`
+	FullName string `json:"full_name"`
+	Email    string `json:"email"`
+}
+
+type GetUserResponse struct {
+	User      User   `json:"user"`
+	SyncToken string `json:"sync_token"`
+}
+
+// User はトークンのアカウントのユーザー情報を取得する
+func (c *Client) User(ctx context.Context) (User, error) {
+	var response GetUserResponse
+	if err := c.sync(ctx, "*", []string{"user"}, &response); err != nil {
+		return User{}, err
+	}
+	return response.User, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+type contentCount struct {
+	Content string
+	Count   int
+}
+
+// 前後の空白を取り除き、連続する空白を1つにする。ignoreCase の場合は小文字にそろえる
+func normalizeContent(content string, ignoreCase bool) string {
+	content = strings.Join(strings.Fields(content), " ")
+	if ignoreCase {
+		content = strings.ToLower(content)
+	}
+	return content
+}
+
+// タスクの内容ごとの完了数を数えて、多い順に n 件を返す
+// 表示にはそれぞれの内容で最初に出てきたものを使う
+func topContents(events []Event, n int, ignoreCase bool) []contentCount {
+	index := make(map[string]int)
+	var counts []contentCount
+	for _, event := range events {
+		key := normalizeContent(event.Content, ignoreCase)
+		i, ok := index[key]
+		if !ok {
+			i = len(counts)
+			index[key] = i
+			counts = append(counts, contentCount{Content: normalizeContent(event.Content, false)})
+		}
+		counts[i].Count++
+	}
+
+	sort.SliceStable(counts, func(i, j int) bool {
+		return counts[i].Count > counts[j].Count
+	})
+	if len(counts) > n {
+		counts = counts[:n]
+	}
+	return counts
+}
+
+func renderTopContents(w io.Writer, counts []contentCount, format string) error {
+	var buf bytes.Buffer
+	switch format {
+	case formatMarkdown:
+		buf.WriteString("\n| Task | Count |\n| --- | --- |\n")
+		for _, c := range counts {
+			fmt.Fprintf(&buf, "| %s | %d |\n", escapeMarkdownCell(c.Content), c.Count)
+		}
+	default:
+		buf.WriteString("\n")
+		for _, c := range counts {
+			fmt.Fprintf(&buf, "%d %s\n", c.Count, c.Content)
+		}
+	}
+
+	if _, err := buf.WriteTo(w); err != nil {
+		return fmt.Errorf("write error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// API リクエストに使う *http.Client を作る
+// --proxy を指定した場合はそのプロキシを使い、指定しない場合は HTTPS_PROXY, HTTP_PROXY, NO_PROXY 環境変数に従う
+func newHTTPClient(proxy string, timeout time.Duration) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("proxy url parse error: %w", err)
+		}
+		if proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return nil, fmt.Errorf("proxy url %q must include a scheme and host (e.g. http://proxy.example.com:8080)", proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
+
+// --base-url はスキームとホストだけを受け付ける。API のパスはクライアント側でつなげる
+func validateBaseURL(baseURL string) error {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return fmt.Errorf("base url parse error: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("base url %q must include an http or https scheme and host (e.g. https://api.todoist.com)", baseURL)
+	}
+	if strings.TrimSuffix(u.Path, "/") != "" || u.RawQuery != "" {
+		return fmt.Errorf("base url %q must not include a path or query", baseURL)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"todoistreport/todoist"
+)
+
+// 親タスクの内容を解決する。レポート対象のイベントに親タスクがあればその内容を使い、
+// なければ未完了のタスクから探す。どちらにもなければ ParentContent は空のままにする
+func resolveParentContents(ctx context.Context, client *todoist.Client, events []Event) error {
+	contents := make(map[string]string)
+	for _, event := range events {
+		contents[event.TaskID] = event.Content
+	}
+
+	unresolved := false
+	for _, event := range events {
+		if _, ok := contents[event.ParentTaskID]; event.ParentTaskID != "" && !ok {
+			unresolved = true
+			break
+		}
+	}
+	if unresolved {
+		response, err := client.Items(ctx)
+		if err != nil {
+			return fmt.Errorf("get items error: %w", err)
+		}
+		for _, item := range response.Items {
+			if _, ok := contents[item.ID]; !ok {
+				contents[item.ID] = item.Content
+			}
+		}
+	}
+
+	for i := range events {
+		if events[i].ParentTaskID == "" {
+			continue
+		}
+		events[i].ParentContent = contents[events[i].ParentTaskID]
+	}
+	return nil
+}
+
+// サブタスクを親タスクの下にインデントして出力する
+// 親タスクがレポートに含まれていない場合は、親タスクの内容を見出しにしてその下に出力する
+// 親タスクが解決できなかったサブタスクはそのまま出力する
+func renderTree(w io.Writer, events []Event, opts renderOptions) error {
+	columns := textColumns(events, opts)
+
+	inReport := make(map[string]bool)
+	for _, event := range events {
+		inReport[event.TaskID] = true
+	}
+	children := make(map[string][]int)
+	for i, event := range events {
+		if event.ParentTaskID != "" && event.ParentContent != "" {
+			children[event.ParentTaskID] = append(children[event.ParentTaskID], i)
+		}
+	}
+
+	var buf strings.Builder
+	printed := make([]bool, len(events))
+	var writeEvent func(i int, depth int)
+	writeEvent = func(i int, depth int) {
+		// 親子関係が循環していても無限ループしないようにする
+		if printed[i] {
+			return
+		}
+		printed[i] = true
+
+		event := events[i]
+		buf.WriteString(strings.Repeat("  ", depth) + textLine(event, columns) + "\n")
+		for _, line := range noteLines(event, strings.Repeat("  ", depth)) {
+			buf.WriteString(line + "\n")
+		}
+
+		if event.TaskID == "" {
+			return
+		}
+		for _, child := range children[event.TaskID] {
+			writeEvent(child, depth+1)
+		}
+	}
+
+	headers := make(map[string]bool)
+	for i, event := range events {
+		parentID := event.ParentTaskID
+		switch {
+		case parentID == "" || event.ParentContent == "":
+			writeEvent(i, 0)
+		case inReport[parentID]:
+			// 親タスクの行の下に出力される
+		case !headers[parentID]:
+			headers[parentID] = true
+			buf.WriteString(event.ParentContent + "\n")
+			for _, child := range children[parentID] {
+				writeEvent(child, 1)
+			}
+		}
+	}
+
+	// 親子関係が循環していて一度も出力されなかったイベントも落とさない
+	for i := range events {
+		writeEvent(i, 0)
+	}
+
+	if _, err := io.WriteString(w, buf.String()); err != nil {
+		return fmt.Errorf("write error: %w", err)
+	}
+	return nil
+}
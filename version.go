@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// ビルド時に -ldflags で埋め込む
+//
+//	go build -ldflags "-X main.version=v1.2.3 -X main.commit=$(git rev-parse --short HEAD) -X main.date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
+)
+
+// -ldflags で埋め込んでいない場合でも、go install でビルドしたならモジュールのバージョンを使う
+func buildVersion() string {
+	if version != "dev" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return version
+}
+
+func versionString() string {
+	return fmt.Sprintf("todoistreport %s (commit %s, built %s, %s %s/%s)", buildVersion(), commit, date, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
+
+func defaultUserAgent() string {
+	return "todoistreport/" + buildVersion()
+}